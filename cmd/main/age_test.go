@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanizeTime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	for _, tc := range []struct {
+		offset time.Duration
+		want   string
+	}{
+		{0, "just now"},
+		{-59 * time.Second, "just now"},
+		{59 * time.Second, "just now"},
+		{-time.Minute, "1 minute ago"},
+		{-59 * time.Minute, "59 minutes ago"},
+		{-time.Hour, "1 hour ago"},
+		{-23*time.Hour - 59*time.Minute, "23 hours ago"},
+		{-day, "1 day ago"},
+		{-29 * day, "29 days ago"},
+		{-30 * day, "1 month ago"},
+		{-364 * day, "12 months ago"},
+		{-365 * day, "1 year ago"},
+		{-3 * 365 * day, "3 years ago"},
+		{time.Minute, "in 1 minute"},
+		{2 * time.Hour, "in 2 hours"},
+		{45 * day, "in 1 month"},
+	} {
+		if got := humanizeTime(now.Add(tc.offset), now); got != tc.want {
+			t.Errorf("humanizeTime(now%+v) = %q, want %q", tc.offset, got, tc.want)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	day := 24 * time.Hour
+	for _, tc := range []struct {
+		value string
+		want  time.Duration
+	}{
+		{"30d", 30 * day},
+		{"2w", 14 * day},
+		{"12h", 12 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"500ms", 500 * time.Millisecond},
+		{"1.5d", 36 * time.Hour},
+		{"1w3d12h", 10*day + 12*time.Hour},
+		{" 7d ", 7 * day},
+	} {
+		got, err := parseAge(tc.value)
+		if err != nil || got != tc.want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", tc.value, got, err, tc.want)
+		}
+	}
+	for _, value := range []string{"", "30", "d", "-1d", "1 w", "3x", "1y", "2w!"} {
+		if got, err := parseAge(value); err == nil {
+			t.Errorf("parseAge(%q) = %v, want an error", value, got)
+		}
+	}
+}
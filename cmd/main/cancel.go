@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Exit codes of a run that was cut short. The output then holds the files
+// processed until that point.
+const (
+	exitTimedOut    = 124 // -timeout expired, as timeout(1) reports it
+	exitInterrupted = 130 // SIGINT or SIGTERM, as shells report Ctrl-C
+)
+
+// newRunContext returns the context a run is canceled through: on SIGINT or
+// SIGTERM, or once timeout has passed when it is positive. After the first
+// signal the default handling is restored, so a second one ends the process
+// at once.
+func newRunContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	cancel := stop
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		cancel = func() {
+			cancelTimeout()
+			stop()
+		}
+	}
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, cancel
+}
+
+// reportCanceled prints why the run stopped early and what the output holds
+// instead of the success message, and returns ctx's error.
+func reportCanceled(ctx context.Context, stats Stats) error {
+	reason := "Interrupted"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = "Timed out (-timeout)"
+	}
+	fmt.Printf("\n%s %s: the output holds the %d files processed before then\n",
+		yellow("⚠"), reason, stats.FilesProcessed)
+	return ctx.Err()
+}
+
+// canceledExitCode returns the exit code for a run that ended with err, and
+// whether err means the run was canceled rather than failed.
+func canceledExitCode(err error) (int, bool) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimedOut, true
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted, true
+	}
+	return 0, false
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// charsPerToken is the rough number of characters per token used by
+// estimateTokens. It matches the usual rule of thumb for English text and
+// source code with common BPE tokenizers.
+const charsPerToken = 4
+
+// estimateTokens returns an approximate token count for content.
+func estimateTokens(content string) int {
+	if content == "" {
+		return 0
+	}
+	return (len(content) + charsPerToken - 1) / charsPerToken
+}
+
+// chunkByTokens groups files into consecutive chunks whose estimated token
+// count stays within limit. Files are never split; a single file larger than
+// limit gets a chunk of its own and is reported in oversized.
+func chunkByTokens(fileInfos []FileInfo, limit int) (chunks [][]FileInfo, oversized []string) {
+	return chunkFiles(fileInfos, int64(limit), func(info FileInfo) int64 {
+		return int64(estimateTokens(info.Content))
+	})
+}
+
+// sectionOverhead approximates the bytes a file's section adds on top of its
+// content and path: separators, the metadata line and markup.
+const sectionOverhead = 256
+
+// chunkBySize groups files into consecutive chunks of about limit bytes of
+// output (-split-size), estimated from each file's content and path. Like
+// chunkByTokens it never splits a file.
+func chunkBySize(fileInfos []FileInfo, limit int64) (chunks [][]FileInfo, oversized []string) {
+	return chunkFiles(fileInfos, limit, func(info FileInfo) int64 {
+		return int64(len(info.Content)+len(info.RelativePath)) + sectionOverhead
+	})
+}
+
+// chunkFiles starts a new chunk whenever the next file's weight would take
+// the current one over limit.
+func chunkFiles(fileInfos []FileInfo, limit int64, weight func(FileInfo) int64) (chunks [][]FileInfo, oversized []string) {
+	var current []FileInfo
+	var currentWeight int64
+
+	for _, info := range fileInfos {
+		w := weight(info)
+		if w > limit {
+			oversized = append(oversized, info.RelativePath)
+		}
+		if len(current) > 0 && currentWeight+w > limit {
+			chunks = append(chunks, current)
+			current, currentWeight = nil, 0
+		}
+		current = append(current, info)
+		currentWeight += w
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks, oversized
+}
+
+// splitsOutput reports whether the output is written as numbered parts.
+func splitsOutput(config Config) bool {
+	return config.ChunkTokens > 0 || config.splitBytes > 0
+}
+
+// partPath returns the path of the n-th part (1-based) of a multi-part output,
+// e.g. "out.txt" becomes "out.part2.txt".
+func partPath(outputPath string, n int) string {
+	ext := filepath.Ext(outputPath)
+	return fmt.Sprintf("%s.part%d%s", strings.TrimSuffix(outputPath, ext), n, ext)
+}
+
+// isPartOf reports whether path is one of the parts partPath derives from
+// outputPath. Both paths must be absolute.
+func isPartOf(path, outputPath string) bool {
+	if filepath.Dir(path) != filepath.Dir(outputPath) {
+		return false
+	}
+	ext := filepath.Ext(outputPath)
+	base := strings.TrimSuffix(filepath.Base(outputPath), ext)
+	re := regexp.MustCompile(`^` + regexp.QuoteMeta(base) + `\.part\d+` + regexp.QuoteMeta(ext) + `(\.gz|\.zst)?(\.partial)?$`)
+	return re.MatchString(filepath.Base(path))
+}
+
+// partialSuffix marks a part that is still being written. It is renamed to
+// the part path only once complete, so a crash never leaves a truncated part
+// under its final name.
+const partialSuffix = ".partial"
+
+// partsManifestPath returns the path of the manifest recording the completed
+// parts of outputPath.
+func partsManifestPath(outputPath string) string {
+	return outputPath + ".parts.json"
+}
+
+// partRecord describes one completed part in the parts manifest.
+type partRecord struct {
+	Path             string `json:"path"`
+	Key              string `json:"key"`
+	Size             int64  `json:"size"`
+	UncompressedSize int64  `json:"uncompressed_size"`
+}
+
+// partsManifest lists the parts completed so far, in order.
+type partsManifest struct {
+	Parts []partRecord `json:"parts"`
+}
+
+// partKey identifies the content of a part: the output format and the path
+// and content of every file in it. A resumed run only reuses a part whose key
+// is unchanged.
+func partKey(chunk []FileInfo, config Config) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", strings.ToLower(config.OutputFormat), config.Compression)
+	for _, info := range chunk {
+		fmt.Fprintf(h, "%s\x00%d\x00", info.RelativePath, len(info.Content))
+		h.Write([]byte(info.Content))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadPartsManifest reads the manifest at path. A missing manifest yields an
+// empty one, so the first run and a resumed run take the same path.
+func loadPartsManifest(path string) (partsManifest, error) {
+	var manifest partsManifest
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// save replaces the manifest at path atomically.
+func (m partsManifest) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + partialSuffix
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// completedPart returns the recorded part n (1-based) when it can be reused:
+// its key matches and the file on disk still has the recorded size.
+func (m partsManifest) completedPart(n int, path, key string) (partRecord, bool) {
+	if n > len(m.Parts) {
+		return partRecord{}, false
+	}
+	record := m.Parts[n-1]
+	if record.Path != path || record.Key != key {
+		return partRecord{}, false
+	}
+	stat, err := os.Stat(path)
+	if err != nil || stat.Size() != record.Size {
+		return partRecord{}, false
+	}
+	return record, true
+}
+
+// writeChunkedOutput writes each chunk to its own part file. Every part is a
+// complete document with its own header and summary describing that part.
+// Local parts are written under partialSuffix and renamed when complete, and
+// each completed part is recorded in the parts manifest; with ResumeParts,
+// parts the manifest shows as already written are kept instead of rewritten.
+// It returns the part paths, the number of parts kept, and the summed
+// on-disk and uncompressed sizes.
+func writeChunkedOutput(chunks [][]FileInfo, config Config, stats Stats) ([]string, int, int64, int64, error) {
+	var parts []string
+	var resumed int
+	var outputSize, uncompressedSize int64
+
+	local := !isRemoteOutput(config.OutputFile)
+	manifestPath := partsManifestPath(config.OutputFile)
+	var previous, manifest partsManifest
+	if local && config.ResumeParts {
+		var err error
+		if previous, err = loadPartsManifest(manifestPath); err != nil {
+			return nil, 0, 0, 0, err
+		}
+	}
+
+	for i, chunk := range chunks {
+		partConfig := config
+		partConfig.OutputFile = partPath(config.OutputFile, i+1)
+		key := partKey(chunk, config)
+
+		if record, ok := previous.completedPart(i+1, partConfig.OutputFile, key); ok {
+			manifest.Parts = append(manifest.Parts, record)
+			parts = append(parts, record.Path)
+			resumed++
+			outputSize += record.Size
+			uncompressedSize += record.UncompressedSize
+			continue
+		}
+		if local {
+			partConfig.OutputFile += partialSuffix
+		}
+
+		partStats := stats
+		partStats.FilesProcessed = len(chunk)
+		partStats.TotalBytes, partStats.TotalLines = 0, 0
+		for _, info := range chunk {
+			partStats.TotalBytes += info.Size
+			partStats.TotalLines += info.LineCount
+		}
+
+		written, rendered, err := writeOutput(chunk, partConfig, partStats)
+		if err != nil {
+			if local {
+				os.Remove(partConfig.OutputFile)
+			}
+			return parts, resumed, outputSize, uncompressedSize, fmt.Errorf("failed to write %s: %w", partConfig.OutputFile, err)
+		}
+		path := strings.TrimSuffix(partConfig.OutputFile, partialSuffix)
+		if local {
+			if err := os.Rename(partConfig.OutputFile, path); err != nil {
+				return parts, resumed, outputSize, uncompressedSize, err
+			}
+			manifest.Parts = append(manifest.Parts, partRecord{Path: path, Key: key, Size: written, UncompressedSize: rendered})
+			if err := manifest.save(manifestPath); err != nil {
+				return parts, resumed, outputSize, uncompressedSize, fmt.Errorf("recording %s: %w", path, err)
+			}
+		}
+		parts = append(parts, path)
+		outputSize += written
+		uncompressedSize += rendered
+	}
+	return parts, resumed, outputSize, uncompressedSize, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSplitOutputNamesParts checks that a split run writes numbered parts
+// named after -output, not -output itself, and lists them in the summary.
+func TestSplitOutputNamesParts(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.txt": strings.Repeat("a", 2000),
+		"b.txt": strings.Repeat("b", 2000),
+	})
+	output := filepath.Join(t.TempDir(), "out.txt")
+
+	out, err := pecelCommand("-input", dir, "-output", output, "-split-size", "2KB", "-plain-summary").CombinedOutput()
+	if err != nil {
+		t.Fatalf("pecel: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("%s was written; split output should only write parts", output)
+	}
+	for n := 1; n <= 2; n++ {
+		part := partPath(output, n)
+		if _, err := os.Stat(part); err != nil {
+			t.Errorf("part %d: %v", n, err)
+		}
+		if row := fmt.Sprintf("Part %d: %s\n", n, part); !strings.Contains(string(out), row) {
+			t.Errorf("summary has no %q row:\n%s", row, out)
+		}
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	for content, want := range map[string]int{
+		"":          0,
+		"a":         1,
+		"abcd":      1,
+		"abcde":     2,
+		"12345678":  2,
+		"123456789": 3,
+	} {
+		if got := estimateTokens(content); got != want {
+			t.Errorf("estimateTokens(%q) = %d, want %d", content, got, want)
+		}
+	}
+}
+
+func TestChunkByTokens(t *testing.T) {
+	// tokens makes a file of n estimated tokens
+	tokens := func(name string, n int) FileInfo {
+		return FileInfo{RelativePath: name, Content: strings.Repeat("x", n*charsPerToken)}
+	}
+	names := func(chunks [][]FileInfo) string {
+		var parts []string
+		for _, chunk := range chunks {
+			var files []string
+			for _, info := range chunk {
+				files = append(files, info.RelativePath)
+			}
+			parts = append(parts, strings.Join(files, ","))
+		}
+		return strings.Join(parts, " | ")
+	}
+	for _, tc := range []struct {
+		name          string
+		files         []FileInfo
+		limit         int
+		want          string
+		wantOversized []string
+	}{
+		{"none", nil, 10, "", nil},
+		{"all fit", []FileInfo{tokens("a", 3), tokens("b", 3), tokens("c", 4)}, 10, "a,b,c", nil},
+		{"split at the limit", []FileInfo{tokens("a", 6), tokens("b", 5), tokens("c", 5)}, 10, "a | b,c", nil},
+		{"order is kept", []FileInfo{tokens("a", 9), tokens("b", 1), tokens("c", 9), tokens("d", 1)}, 10, "a,b | c,d", nil},
+		{"oversized alone", []FileInfo{tokens("a", 2), tokens("big", 25), tokens("b", 2)}, 10, "a | big | b", []string{"big"}},
+		{"oversized first", []FileInfo{tokens("big", 11), tokens("a", 1)}, 10, "big | a", []string{"big"}},
+		{"empty files", []FileInfo{tokens("a", 10), tokens("e", 0)}, 10, "a,e", nil},
+	} {
+		chunks, oversized := chunkByTokens(tc.files, tc.limit)
+		if got := names(chunks); got != tc.want {
+			t.Errorf("%s: chunks %q, want %q", tc.name, got, tc.want)
+		}
+		if strings.Join(oversized, ",") != strings.Join(tc.wantOversized, ",") {
+			t.Errorf("%s: oversized %v, want %v", tc.name, oversized, tc.wantOversized)
+		}
+	}
+}
+
+func TestCompletedPart(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.part1.txt")
+	if err := os.WriteFile(path, []byte("12345"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest := partsManifest{Parts: []partRecord{{Path: path, Key: "k", Size: 5}}}
+	for _, tc := range []struct {
+		name      string
+		n         int
+		path, key string
+		want      bool
+	}{
+		{"unchanged", 1, path, "k", true},
+		{"not recorded", 2, partPath(filepath.Join(dir, "out.txt"), 2), "k", false},
+		{"other path", 1, filepath.Join(dir, "other.txt"), "k", false},
+		{"other content", 1, path, "changed", false},
+	} {
+		if _, got := manifest.completedPart(tc.n, tc.path, tc.key); got != tc.want {
+			t.Errorf("%s: completedPart = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// A part whose size on disk changed is written again
+	if err := os.WriteFile(path, []byte("123456"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manifest.completedPart(1, path, "k"); ok {
+		t.Error("completedPart kept a part whose size changed")
+	}
+}
+
+// TestWriteChunkedOutputResume writes three parts, then resumes after one
+// part's files changed and another part was damaged on disk, and checks
+// that only the intact, unchanged part is kept.
+func TestWriteChunkedOutputResume(t *testing.T) {
+	config := defaultConfig()
+	config.OutputFile = filepath.Join(t.TempDir(), "out.txt")
+	config.ChunkTokens = 100
+	chunks := [][]FileInfo{
+		{{RelativePath: "a.txt", Content: "alpha\n"}},
+		{{RelativePath: "b.txt", Content: "bravo\n"}},
+		{{RelativePath: "c.txt", Content: "charlie\n"}},
+	}
+	parts, resumed, _, _, err := writeChunkedOutput(chunks, config, Stats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 3 || resumed != 0 {
+		t.Fatalf("first run wrote %v and kept %d parts", parts, resumed)
+	}
+	for _, part := range parts {
+		if _, err := os.Stat(part + partialSuffix); !os.IsNotExist(err) {
+			t.Errorf("%s was left behind", part+partialSuffix)
+		}
+	}
+
+	// Mark part 1 so keeping it shows, change part 2's files and grow part 3
+	part1, _ := os.ReadFile(parts[0])
+	marked := strings.Repeat("#", len(part1))
+	if err := os.WriteFile(parts[0], []byte(marked), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chunks[1][0].Content = "bravo, changed\n"
+	if f, err := os.OpenFile(parts[2], os.O_APPEND|os.O_WRONLY, 0); err == nil {
+		f.WriteString("damage")
+		f.Close()
+	}
+
+	config.ResumeParts = true
+	parts, resumed, _, _, err = writeChunkedOutput(chunks, config, Stats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed != 1 {
+		t.Errorf("kept %d parts, want 1", resumed)
+	}
+	if data, _ := os.ReadFile(parts[0]); string(data) != marked {
+		t.Error("part 1 was rewritten although it was complete and unchanged")
+	}
+	if data, _ := os.ReadFile(parts[1]); !strings.Contains(string(data), "bravo, changed") {
+		t.Errorf("part 2 was not rewritten with its changed file:\n%s", data)
+	}
+	if data, _ := os.ReadFile(parts[2]); strings.Contains(string(data), "damage") {
+		t.Error("part 3 was kept although its size changed")
+	}
+
+	// Without -resume-parts every part is written again
+	config.ResumeParts = false
+	if _, resumed, _, _, err = writeChunkedOutput(chunks, config, Stats{}); err != nil || resumed != 0 {
+		t.Errorf("run without -resume-parts kept %d parts (err %v)", resumed, err)
+	}
+	if data, _ := os.ReadFile(parts[0]); string(data) == marked {
+		t.Error("part 1 was kept without -resume-parts")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codecs accepted by -compression.
+const (
+	compressionNone = "none"
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+// compressionExtensions is the suffix each codec adds to the output path.
+var compressionExtensions = map[string]string{
+	compressionGzip: ".gz",
+	compressionZstd: ".zst",
+}
+
+// compressionLevels is the -compression-level range each codec accepts.
+var compressionLevels = map[string][2]int{
+	compressionGzip: {gzip.BestSpeed, gzip.BestCompression},
+	compressionZstd: {1, 22},
+}
+
+// validCompressionLevel reports whether level is usable with codec; 0 picks
+// the codec's default.
+func validCompressionLevel(codec string, level int) error {
+	if level == 0 {
+		return nil
+	}
+	bounds, ok := compressionLevels[codec]
+	if !ok {
+		return fmt.Errorf("no codec selected; use -compression gzip or zstd")
+	}
+	if level < bounds[0] || level > bounds[1] {
+		return fmt.Errorf("%s levels range from %d to %d", codec, bounds[0], bounds[1])
+	}
+	return nil
+}
+
+// newCompressor wraps w in the configured codec.
+func newCompressor(w io.Writer, config Config, stats Stats) (io.WriteCloser, error) {
+	if config.Compression == compressionZstd {
+		return newZstdWriter(w, config)
+	}
+	return newGzipWriter(w, config, stats)
+}
+
+// newZstdWriter returns a zstd compressor using -compress-workers goroutines,
+// or -parallel when unset. zstd levels 1-22 map onto the encoder's four
+// speed presets.
+func newZstdWriter(w io.Writer, config Config) (io.WriteCloser, error) {
+	workers := config.CompressWorkers
+	if workers == 0 {
+		workers = config.Parallel
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	opts := []zstd.EOption{zstd.WithEncoderConcurrency(workers)}
+	if config.CompressionLevel != 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(config.CompressionLevel)))
+	}
+	return zstd.NewWriter(w, opts...)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/klauspost/pgzip"
+)
+
+// sourceCorpus returns size bytes of this package's source, repeated, as
+// input that compresses like a typical bundle.
+func sourceCorpus(tb testing.TB, size int) []byte {
+	tb.Helper()
+	src, err := os.ReadFile("main.go")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return bytes.Repeat(src, size/len(src)+1)[:size]
+}
+
+// TestNewGzipWriterPicksSingleStreamForSmallInputs checks that outputs below
+// parallelGzipThreshold keep compress/gzip whatever the worker count, and
+// that both writers produce a standard gzip stream.
+func TestNewGzipWriterPicksSingleStreamForSmallInputs(t *testing.T) {
+	for _, tc := range []struct {
+		total    int64
+		workers  int
+		parallel bool
+	}{
+		{total: 64 << 10, workers: 8},
+		{total: parallelGzipThreshold - 1, workers: 8},
+		{total: parallelGzipThreshold, workers: 1},
+		{total: parallelGzipThreshold, workers: 8, parallel: true},
+	} {
+		config := Config{CompressWorkers: tc.workers}
+		var out bytes.Buffer
+		w, err := newGzipWriter(&out, config, Stats{TotalBytes: tc.total})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, isParallel := w.(*pgzip.Writer); isParallel != tc.parallel {
+			t.Errorf("%d bytes on %d workers: pgzip = %v, want %v", tc.total, tc.workers, isParallel, tc.parallel)
+		}
+
+		input := sourceCorpus(t, 3<<20)
+		if _, err := w.Write(input); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		r, err := gzip.NewReader(&out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(got, input) {
+			t.Errorf("%d bytes on %d workers: output does not decompress to the input (%v)", tc.total, tc.workers, err)
+		}
+	}
+}
+
+// BenchmarkGzip compares compress/gzip with pgzip at several block sizes
+// over inputs around parallelGzipThreshold, using one worker per CPU.
+func BenchmarkGzip(b *testing.B) {
+	workers := runtime.NumCPU()
+	for _, size := range []int{256 << 10, 1 << 20, 8 << 20, 64 << 20} {
+		input := sourceCorpus(b, size)
+		b.Run(fmt.Sprintf("%dKiB/gzip", size>>10), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				w := gzip.NewWriter(io.Discard)
+				w.Write(input)
+				w.Close()
+			}
+		})
+		for _, block := range []int{256 << 10, parallelGzipBlockSize, 4 << 20} {
+			b.Run(fmt.Sprintf("%dKiB/pgzip-%dKiB", size>>10, block>>10), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					w := pgzip.NewWriter(io.Discard)
+					if err := w.SetConcurrency(block, workers); err != nil {
+						b.Fatal(err)
+					}
+					w.Write(input)
+					w.Close()
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestFlagsOverrideConfigFile checks that flags given on the command line
+// override a configuration file even when they carry their default value.
+func TestFlagsOverrideConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.go":      "package a\n",
+		"a_test.go": "package a\n",
+		"notes.txt": "notes\n",
+	})
+	configFile := filepath.Join(t.TempDir(), "pecel.json")
+	writeTree(t, filepath.Dir(configFile), map[string]string{
+		"pecel.json": `{"extensions": [".go"], "exclude_tests": true, "output_format": "json", "walk_parallel": 4, "max_file_size": 3}`,
+	})
+	output := filepath.Join(t.TempDir(), "out.txt")
+
+	out, err := pecelCommand("-config", configFile, "-input", dir, "-output", output, "-quiet",
+		"-ext", "", "-exclude-tests=false", "-format", "text", "-walk-parallel", "1", "-max-size", "0").CombinedOutput()
+	if err != nil {
+		t.Fatalf("pecel: %v\n%s", err, out)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.go", "a_test.go", "notes.txt"} {
+		if !strings.Contains(string(data), "\n"+name+"\n") {
+			t.Errorf("text output has no %s:\n%s", name, data)
+		}
+	}
+}
+
+// flagsWithoutOverride are the flags that set no configuration file setting,
+// so applyFlagOverrides has nothing to do for them.
+var flagsWithoutOverride = map[string]string{
+	"config":                          "names the configuration files",
+	"no-config":                       "controls configuration file discovery",
+	"profile":                         "is applied to the configuration files",
+	"i":                               "is copied into -input",
+	"o":                               "is copied into -output",
+	"eh":                              "is copied into -exclude-hidden",
+	"v":                               "is copied into -version",
+	"version":                         "prints the version and exits",
+	"list-formats":                    "lists the formats and exits",
+	"no-color":                        "only affects messages",
+	"interactive-filter-test":         "runs the filter preview",
+	"scan-extensions":                 "prints an extension report and exits",
+	"output-json-streaming-to-stdout": "selects the streaming mode",
+}
+
+// overriddenConfigs applies the flag overrides to a zero Config and to one
+// whose exported fields all hold a placeholder, so that a flag set to the zero
+// value of its field shows up as a change too.
+func overriddenConfigs() [2]Config {
+	var zero, filled Config
+	v := reflect.ValueOf(&filled).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		switch f := v.Field(i); f.Kind() {
+		case reflect.Bool:
+			f.SetBool(true)
+		case reflect.Int, reflect.Int64:
+			f.SetInt(-1)
+		case reflect.Float64:
+			f.SetFloat(-1)
+		case reflect.String:
+			f.SetString("placeholder")
+		case reflect.Slice:
+			f.Set(reflect.ValueOf([]string{"placeholder"}))
+		}
+	}
+	applyFlagOverrides(&zero)
+	applyFlagOverrides(&filled)
+	return [2]Config{zero, filled}
+}
+
+// TestEveryFlagOverridesConfigFile gives each flag a value other than its
+// default and checks that applyFlagOverrides carries it into the Config, so a
+// new flag cannot be left out of the configuration file branch.
+func TestEveryFlagOverridesConfigFile(t *testing.T) {
+	// Flags are set one at a time and stay set, so each step compares the
+	// overrides with and without the newest flag.
+	before := overriddenConfigs()
+	flag.VisitAll(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Name, "test.") {
+			return
+		}
+		if _, ok := flagsWithoutOverride[f.Name]; ok {
+			return
+		}
+		var value string
+		switch v := f.Value.(flag.Getter).Get().(type) {
+		case bool:
+			value = fmt.Sprint(!v)
+		case int, int64:
+			value = fmt.Sprint(v) + "7"
+		case float64:
+			value = fmt.Sprint(v + 0.25)
+		case string:
+			value = v + "x"
+		default:
+			t.Errorf("-%s has a value of type %T the test cannot set", f.Name, v)
+			return
+		}
+		if err := flag.Set(f.Name, value); err != nil {
+			t.Errorf("setting -%s: %v", f.Name, err)
+			return
+		}
+		after := overriddenConfigs()
+		if reflect.DeepEqual(before, after) {
+			t.Errorf("-%s does not override the configuration file; add it to applyFlagOverrides", f.Name)
+		}
+		before = after
+	})
+}
+
+// TestApplyProfileCanSwitchSettingsOff checks that every key a profile gives
+// applies, including false, 0 and empty lists, in JSON and YAML files.
+func TestApplyProfileCanSwitchSettingsOff(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"pecel.json": `{
+			"keep_binaries": true,
+			"max_file_size": 5,
+			"extensions": [".go"],
+			"output_format": "json",
+			"profiles": {"all": {"exclude_hidden": false, "keep_binaries": false, "max_file_size": 0, "extensions": []}}
+		}`,
+		"pecel.yaml": "keep_binaries: true\nmax_file_size: 5\nextensions: [.go]\noutput_format: json\n" +
+			"profiles:\n  all:\n    exclude_hidden: false\n    keep_binaries: false\n    max_file_size: 0\n    extensions: []\n",
+	})
+	for _, name := range []string{"pecel.json", "pecel.yaml"} {
+		cfg, err := loadConfigs([]string{filepath.Join(dir, name)})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !cfg.ExcludeHidden {
+			t.Fatalf("%s: exclude_hidden should default to true before the profile", name)
+		}
+		cfg, err = applyProfile(cfg, "all")
+		if err != nil {
+			t.Fatalf("%s: applyProfile: %v", name, err)
+		}
+		if cfg.ExcludeHidden || cfg.KeepBinaries || cfg.MaxFileSize != 0 || len(cfg.Extensions) != 0 {
+			t.Errorf("%s: profile did not switch its settings off: %+v", name, cfg)
+		}
+		if cfg.OutputFormat != "json" {
+			t.Errorf("%s: output_format = %q, want the file's json kept", name, cfg.OutputFormat)
+		}
+		if _, err := applyProfile(cfg, "none"); err == nil || !strings.Contains(err.Error(), "available: all") {
+			t.Errorf("%s: applyProfile with an unknown name: %v", name, err)
+		}
+	}
+}
+
+// TestLoadConfigsLaterLayersReset checks that a later configuration file can
+// switch off, clear or zero what an earlier one set, and leaves the settings
+// it does not mention alone.
+func TestLoadConfigsLaterLayersReset(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"team.json": `{"exclude_hidden": true, "keep_binaries": true, "extensions": [".go"], "max_file_size": 100,
+			"output_format": "json", "profiles": {"a": {"verbose": true}, "b": {"quiet": true}}}`,
+		"personal.yaml": "exclude_hidden: false\nkeep_binaries: false\nextensions: []\nmax_file_size: 0\n" +
+			"profiles:\n  b:\n    dry_run: true\n",
+	})
+	cfg, err := loadConfigs([]string{filepath.Join(dir, "team.json"), filepath.Join(dir, "personal.yaml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ExcludeHidden || cfg.KeepBinaries || len(cfg.Extensions) != 0 || cfg.MaxFileSize != 0 {
+		t.Errorf("the later file did not reset the earlier settings: %+v", cfg)
+	}
+	if cfg.OutputFormat != "json" {
+		t.Errorf("output_format = %q, want the earlier file's json", cfg.OutputFormat)
+	}
+	if len(cfg.Profiles) != 2 || !strings.Contains(string(cfg.Profiles["b"]), "dry_run") {
+		t.Errorf("profiles = %s, want a from the first file and b from the second", cfg.Profiles)
+	}
+}
+
+func TestConfigIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		// a relative include resolves from the including file's directory
+		"relative.json":       `{"include": ["sub/child.json"]}`,
+		"sub/child.json":      `{"include": ["grandchild.json"]}`,
+		"sub/grandchild.json": `{}`,
+		// a diamond loads the shared file once per path to it
+		"diamond.json": `{"include": ["left.json", "right.json"]}`,
+		"left.json":    `{"include": ["shared.json"]}`,
+		"right.json":   `{"include": ["shared.json"]}`,
+		"shared.json":  `{}`,
+		"a.json":       `{"include": ["b.json"]}`,
+		"b.json":       `{"include": ["a.json"]}`,
+		"self.json":    `{"include": ["self.json"]}`,
+	})
+	path := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+
+	for _, tc := range []struct {
+		file    string
+		want    []string
+		wantErr string
+	}{
+		{file: "relative.json", want: []string{"sub/grandchild.json", "sub/child.json", "relative.json"}},
+		{file: "diamond.json", want: []string{"shared.json", "left.json", "shared.json", "right.json", "diamond.json"}},
+		{file: "a.json", wantErr: "configuration include cycle: " + path("a.json") + " -> " + path("b.json") + " -> " + path("a.json")},
+		{file: "self.json", wantErr: "configuration include cycle: " + path("self.json") + " -> " + path("self.json")},
+	} {
+		got, err := configIncludes(path(tc.file), nil)
+		if tc.wantErr != "" {
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("configIncludes(%s) error = %v, want %q", tc.file, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("configIncludes(%s): %v", tc.file, err)
+			continue
+		}
+		want := make([]string, len(tc.want))
+		for i, name := range tc.want {
+			want[i] = path(name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("configIncludes(%s) = %v, want %v", tc.file, got, want)
+		}
+	}
+}
+
+// TestLoadConfigsIncludesMergeFirst checks that a file's own settings
+// override the ones it includes.
+func TestLoadConfigsIncludesMergeFirst(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"base.json":    `{"output_format": "json", "parallel": 3}`,
+		"project.json": `{"include": ["base.json"], "output_format": "xml"}`,
+	})
+	config, err := loadConfigs([]string{filepath.Join(dir, "project.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.OutputFormat != "xml" || config.Parallel != 3 || config.Include != nil {
+		t.Errorf("got format %q, parallel %d, include %v; want xml, 3 and none", config.OutputFormat, config.Parallel, config.Include)
+	}
+}
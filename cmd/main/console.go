@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+// setColorOutput turns colored messages off for -no-color, a non-empty
+// NO_COLOR (https://no-color.org), TERM=dumb, or a stdout that is not a
+// terminal. It runs again whenever stdout is pointed at stderr, so the check
+// follows where messages actually go.
+func setColorOutput(disabled bool) {
+	color.NoColor = disabled || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" ||
+		!term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// console serializes the messages worker goroutines print. Workers send
+// whole lines over a channel and a single goroutine writes them, so lines
+// from different workers never interleave however many there are. It also
+// keeps the progress bar's status line at the bottom: each message clears
+// it and redraws it underneath.
+type console struct {
+	lines chan consoleLine
+	done  chan struct{}
+}
+
+// consoleLine is a message, or the new status line when status is set.
+type consoleLine struct {
+	text   string
+	status bool
+}
+
+// clearLine returns the cursor to the start of the line and erases it.
+const clearLine = "\r\x1b[K"
+
+// consoleBuffer is how many lines workers can queue before they wait for the
+// writer.
+const consoleBuffer = 64
+
+// startConsole starts the goroutine that writes the lines.
+func startConsole() *console {
+	c := &console{
+		lines: make(chan consoleLine, consoleBuffer),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(c.done)
+		status := ""
+		for line := range c.lines {
+			switch {
+			case line.status:
+				status = line.text
+				os.Stdout.WriteString(clearLine + status)
+			case status != "":
+				os.Stdout.WriteString(clearLine + line.text + status)
+			default:
+				os.Stdout.WriteString(line.text)
+			}
+		}
+		if status != "" {
+			os.Stdout.WriteString("\n")
+		}
+	}()
+	return c
+}
+
+// printf formats a message like fmt.Printf and queues it. Outside the
+// workers, where c is nil, it prints directly.
+func (c *console) printf(format string, args ...interface{}) {
+	if c == nil {
+		fmt.Printf(format, args...)
+		return
+	}
+	c.lines <- consoleLine{text: fmt.Sprintf(format, args...)}
+}
+
+// setStatus replaces the status line shown below the messages.
+func (c *console) setStatus(text string) {
+	c.lines <- consoleLine{text: text, status: true}
+}
+
+// close waits for every queued line to be written. Nothing may be printed
+// through c afterwards.
+func (c *console) close() {
+	close(c.lines)
+	<-c.done
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// writeCSVOutput writes -format csv: a header row, then one row per file
+// with its path, relative path, size, modification time and line count, plus
+// its content with -csv-include-content. The line count is left empty for
+// entries without countable text (symlinks, metadata-only entries and
+// encoded binaries).
+func writeCSVOutput(fileInfos []FileInfo, writer io.Writer, config Config) (int64, error) {
+	counter := &countingWriter{w: writer}
+	w := csv.NewWriter(counter)
+
+	header := []string{"path", "relative_path", "size", "modified", "lines"}
+	if config.CSVIncludeContent {
+		header = append(header, "content")
+	}
+	if err := w.Write(header); err != nil {
+		return counter.n, err
+	}
+
+	for _, info := range fileInfos {
+		lines := ""
+		if info.Encoding == "" && !info.ContentOmitted && info.LinkTarget == "" {
+			count := info.LineCount
+			if !config.LineCounts {
+				count = countLines([]byte(info.Content))
+			}
+			lines = strconv.Itoa(count)
+		}
+		record := []string{info.Path, info.RelativePath, strconv.FormatInt(info.Size, 10), info.Modified, lines}
+		if config.CSVIncludeContent {
+			record = append(record, info.Content)
+		}
+		if err := w.Write(record); err != nil {
+			return counter.n, err
+		}
+	}
+	w.Flush()
+	return counter.n, w.Error()
+}
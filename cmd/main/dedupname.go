@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Strategies for picking the file kept per base name (-dedup-by-name).
+const (
+	dedupFirst   = "first"
+	dedupLargest = "largest"
+	dedupNewest  = "newest"
+)
+
+// validateDedupByName checks a -dedup-by-name value; empty turns it off.
+func validateDedupByName(strategy string) error {
+	switch strategy {
+	case "", dedupFirst, dedupLargest, dedupNewest:
+		return nil
+	}
+	return fmt.Errorf("unknown strategy %q (want first, largest or newest)", strategy)
+}
+
+// dedupByName keeps one entry per base name and returns the kept entries in
+// their original order with the number dropped. The kept entry is the first
+// in walk order, the largest or the most recently modified, according to
+// strategy; ties go to the earlier entry.
+func dedupByName(entries []walkEntry, strategy string) ([]walkEntry, int) {
+	chosen := make(map[string]int)
+	for i, entry := range entries {
+		name := filepath.Base(entry.Path)
+		j, seen := chosen[name]
+		if !seen || preferEntry(entry, entries[j], strategy) {
+			chosen[name] = i
+		}
+	}
+
+	kept := make([]walkEntry, 0, len(chosen))
+	for i, entry := range entries {
+		if chosen[filepath.Base(entry.Path)] == i {
+			kept = append(kept, entry)
+		}
+	}
+	return kept, len(entries) - len(kept)
+}
+
+// preferEntry reports whether candidate replaces current as the kept file.
+func preferEntry(candidate, current walkEntry, strategy string) bool {
+	switch strategy {
+	case dedupLargest:
+		return candidate.Size > current.Size
+	case dedupNewest:
+		return candidate.ModTime.After(current.ModTime)
+	}
+	return false
+}
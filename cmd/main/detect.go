@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// utf8BOM is the byte order mark some editors, mostly on Windows, put at the
+// start of UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// stripBOM removes a leading UTF-8 byte order mark, which would otherwise end
+// up in the middle of the combined output.
+func stripBOM(content []byte) []byte {
+	return bytes.TrimPrefix(content, utf8BOM)
+}
+
+// sniffLen is how much of a file http.DetectContentType looks at.
+const sniffLen = 512
+
+// detectContentType returns the MIME type of content based on its leading
+// bytes, independent of the file's extension.
+func detectContentType(content []byte) string {
+	if len(content) > sniffLen {
+		content = content[:sniffLen]
+	}
+	return http.DetectContentType(content)
+}
+
+// extensionLanguages maps file extensions to markdown fence languages.
+var extensionLanguages = map[string]string{
+	".go":         "go",
+	".py":         "python",
+	".js":         "javascript",
+	".mjs":        "javascript",
+	".cjs":        "javascript",
+	".jsx":        "jsx",
+	".ts":         "typescript",
+	".tsx":        "tsx",
+	".java":       "java",
+	".kt":         "kotlin",
+	".kts":        "kotlin",
+	".scala":      "scala",
+	".rs":         "rust",
+	".c":          "c",
+	".h":          "c",
+	".cc":         "cpp",
+	".cpp":        "cpp",
+	".cxx":        "cpp",
+	".hpp":        "cpp",
+	".cs":         "csharp",
+	".swift":      "swift",
+	".m":          "objectivec",
+	".rb":         "ruby",
+	".php":        "php",
+	".pl":         "perl",
+	".lua":        "lua",
+	".r":          "r",
+	".dart":       "dart",
+	".ex":         "elixir",
+	".exs":        "elixir",
+	".erl":        "erlang",
+	".hs":         "haskell",
+	".clj":        "clojure",
+	".sh":         "bash",
+	".bash":       "bash",
+	".zsh":        "zsh",
+	".fish":       "fish",
+	".ps1":        "powershell",
+	".sql":        "sql",
+	".html":       "html",
+	".htm":        "html",
+	".css":        "css",
+	".scss":       "scss",
+	".sass":       "sass",
+	".less":       "less",
+	".vue":        "vue",
+	".svelte":     "svelte",
+	".json":       "json",
+	".xml":        "xml",
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".toml":       "toml",
+	".ini":        "ini",
+	".md":         "markdown",
+	".proto":      "protobuf",
+	".tf":         "hcl",
+	".graphql":    "graphql",
+	".dockerfile": "dockerfile",
+	".mk":         "makefile",
+}
+
+// parseLangMap parses a -lang-map value such as ".vue=html,.tpl=jinja" into a
+// map from extension, with its leading dot and lower-cased, to fence
+// language. An empty language leaves those fences bare.
+func parseLangMap(value string) (map[string]string, error) {
+	langs := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		ext, lang, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		ext, lang = strings.TrimSpace(ext), strings.TrimSpace(lang)
+		if !ok || strings.TrimPrefix(ext, ".") == "" {
+			return nil, fmt.Errorf("%q is not of the form .ext=lang", pair)
+		}
+		langs["."+strings.ToLower(strings.TrimPrefix(ext, "."))] = lang
+	}
+	return langs, nil
+}
+
+// extensionLanguage returns the fence language for relPath's extension, from
+// langMap first and the built-in table second.
+func extensionLanguage(relPath string, langMap map[string]string) (string, bool) {
+	ext := strings.ToLower(filepath.Ext(relPath))
+	if ext == "" {
+		return "", false
+	}
+	if lang, ok := langMap[ext]; ok {
+		return lang, true
+	}
+	lang, ok := extensionLanguages[ext]
+	return lang, ok
+}
+
+// contentTypeLanguages maps sniffed MIME types to markdown fence languages.
+var contentTypeLanguages = map[string]string{
+	"text/html":              "html",
+	"text/xml":               "xml",
+	"application/xml":        "xml",
+	"application/json":       "json",
+	"application/javascript": "javascript",
+	"image/svg+xml":          "xml",
+}
+
+// shebangLanguages maps script interpreters to markdown fence languages.
+var shebangLanguages = map[string]string{
+	"sh":      "sh",
+	"bash":    "bash",
+	"zsh":     "zsh",
+	"python":  "python",
+	"python3": "python",
+	"node":    "javascript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+}
+
+// contentLanguage infers a fence language from content alone: a script's
+// shebang line first, then its sniffed content type.
+func contentLanguage(content, contentType string) string {
+	if strings.HasPrefix(content, "#!") {
+		line := content
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+		if len(fields) > 0 {
+			interpreter := path.Base(fields[0])
+			if interpreter == "env" && len(fields) > 1 {
+				interpreter = fields[1]
+			}
+			if lang, ok := shebangLanguages[interpreter]; ok {
+				return lang
+			}
+		}
+	}
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	return contentTypeLanguages[mediaType]
+}
+
+// defaultLargeBinaryThreshold is the size above which binary-looking files are
+// skipped unless the guard is turned off.
+const defaultLargeBinaryThreshold = 10 * 1024 * 1024
+
+// binarySniffLen is how much of a file looksBinary inspects.
+const binarySniffLen = 8000
+
+// defaultBinaryThreshold is the share of non-text bytes above which a sample
+// counts as binary, the cutoff Perl's -B file test uses.
+const defaultBinaryThreshold = 0.3
+
+// binaryThreshold returns the configured -binary-threshold, falling back to
+// the default when none is set.
+func binaryThreshold(config Config) float64 {
+	if config.BinaryThreshold > 0 {
+		return config.BinaryThreshold
+	}
+	return defaultBinaryThreshold
+}
+
+// largeBinaryThreshold returns the configured large-binary cutoff, falling
+// back to the default when none is set.
+func largeBinaryThreshold(config Config) int64 {
+	if config.LargeBinaryThreshold > 0 {
+		return config.LargeBinaryThreshold
+	}
+	return defaultLargeBinaryThreshold
+}
+
+// looksBinary reports whether the start of the file at path looks binary by
+// isBinaryContent under the configured threshold. Unreadable files are left
+// for the reader to report.
+func looksBinary(path string, config Config) bool {
+	f, err := openInput(path, config)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, binarySniffLen)
+	n, _ := io.ReadFull(f, buf)
+	return isBinaryContent(buf[:n], binaryThreshold(config))
+}
+
+// isBinaryContent reports whether the start of content is binary: it holds a
+// NUL byte, or more than threshold of its bytes are not text. Bytes that are
+// not text are those of invalid UTF-8 sequences and control characters other
+// than whitespace, backspace and escape.
+func isBinaryContent(content []byte, threshold float64) bool {
+	buf := content[:min(len(content), binarySniffLen)]
+	if bytes.IndexByte(buf, 0) >= 0 {
+		return true
+	}
+	// Don't count a multi-byte rune cut off at the end of the sample
+	if len(buf) == binarySniffLen {
+		for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(buf[i]) {
+				if !utf8.FullRune(buf[i:]) {
+					buf = buf[:i]
+				}
+				break
+			}
+		}
+	}
+	if len(buf) == 0 {
+		return false
+	}
+
+	nonText := 0
+	for i := 0; i < len(buf); {
+		r, size := utf8.DecodeRune(buf[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			nonText++
+		case r < 0x20 && !strings.ContainsRune("\t\n\v\f\r\b\x1b", r), r == 0x7f:
+			nonText++
+		}
+		i += size
+	}
+	return float64(nonText)/float64(len(buf)) > threshold
+}
+
+// countLines returns the number of lines in content; a final line without a
+// trailing newline still counts.
+func countLines(content []byte) int {
+	lines := bytes.Count(content, []byte{'\n'})
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
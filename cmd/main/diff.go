@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Diff statuses recorded on FileInfo.DiffStatus.
+const (
+	diffAdded     = "added"
+	diffModified  = "modified"
+	diffUnchanged = "unchanged"
+)
+
+// DiffSummary describes how the current file set differs from a previous
+// bundle. Removed files no longer exist, so they are only listed here.
+type DiffSummary struct {
+	Added     int      `json:"added" xml:"added"`
+	Modified  int      `json:"modified" xml:"modified"`
+	Unchanged int      `json:"unchanged" xml:"unchanged"`
+	Removed   []string `json:"removed" xml:"removed>path"`
+}
+
+// loadPreviousBundle reads the files of a JSON output written by an earlier
+// run, or the path to hash object written by -content-hash-only.
+func loadPreviousBundle(path string) ([]FileInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(file).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if files, ok := doc["files"]; ok {
+		var bundle []FileInfo
+		err := json.Unmarshal(files, &bundle)
+		return bundle, err
+	}
+
+	hashes := make([]FileInfo, 0, len(doc))
+	for relPath, raw := range doc {
+		info := FileInfo{RelativePath: relPath}
+		if err := json.Unmarshal(raw, &info.Hash); err != nil {
+			return nil, fmt.Errorf("%s: %q is neither a bundle nor a path to hash map", path, relPath)
+		}
+		hashes = append(hashes, info)
+	}
+	return hashes, nil
+}
+
+// diffBundles annotates each current file with its diff status and returns the
+// summary. Files are matched by relative path and compared by content hash;
+// bundles written without -hash are hashed from their inlined content.
+func diffBundles(current []FileInfo, previous []FileInfo) *DiffSummary {
+	prevHashes := make(map[string]string, len(previous))
+	for _, info := range previous {
+		hash := info.Hash
+		if hash == "" {
+			sum := sha256.Sum256([]byte(info.Content))
+			hash = hex.EncodeToString(sum[:])
+		}
+		prevHashes[info.RelativePath] = hash
+	}
+
+	summary := &DiffSummary{Removed: []string{}}
+	seen := make(map[string]bool, len(current))
+	for i := range current {
+		info := &current[i]
+		seen[info.RelativePath] = true
+		prevHash, existed := prevHashes[info.RelativePath]
+		switch {
+		case !existed:
+			info.DiffStatus = diffAdded
+			summary.Added++
+		case prevHash != info.Hash:
+			info.DiffStatus = diffModified
+			summary.Modified++
+		default:
+			info.DiffStatus = diffUnchanged
+			summary.Unchanged++
+		}
+	}
+
+	for path := range prevHashes {
+		if !seen[path] {
+			summary.Removed = append(summary.Removed, path)
+		}
+	}
+	sort.Strings(summary.Removed)
+	return summary
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// documentData is what -header-template-file and -footer-template-file
+// templates are executed with. OutputSize and Omitted are only known when
+// the footer is rendered; in the header they are zero. OutputSize is the
+// size of the document written before the footer, not of the final file.
+type documentData struct {
+	Generated  string
+	Format     string
+	Stats      Stats
+	Config     Config
+	OutputSize int64
+	Omitted    int
+}
+
+func newDocumentData(config Config, stats Stats) documentData {
+	return documentData{
+		Generated: time.Now().Format("2006-01-02 15:04:05"),
+		Format:    config.OutputFormat,
+		Stats:     stats,
+		Config:    config,
+	}
+}
+
+// loadDocumentTemplate parses a header or footer template and executes it
+// once against empty data, so mistakes such as unknown fields fail before
+// any work is done.
+func loadDocumentTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// A BOM saved by the editor would otherwise open the output
+	tmpl, err := template.New(filepath.Base(path)).Funcs(template.FuncMap{
+		"bytes": formatBytes,
+	}).Parse(string(stripBOM(data)))
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, documentData{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// documentText renders tmpl with data, or returns builtin when no template
+// was given.
+func documentText(tmpl *template.Template, data documentData, builtin string) (string, error) {
+	if tmpl == nil {
+		return builtin, nil
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
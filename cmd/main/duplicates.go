@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DuplicateGroup is a set of files with identical content.
+type DuplicateGroup struct {
+	Hash  string   `json:"hash"`
+	Size  int64    `json:"size"`
+	Paths []string `json:"paths"`
+}
+
+// Wasted is the space taken by all copies but one.
+func (g DuplicateGroup) Wasted() int64 {
+	return g.Size * int64(len(g.Paths)-1)
+}
+
+// findDuplicates groups files by content hash and returns the groups with
+// more than one member, most wasted space first. Files without a hash (not
+// read, or hashing disabled) are ignored.
+func findDuplicates(fileInfos []FileInfo) []DuplicateGroup {
+	byHash := make(map[string]*DuplicateGroup)
+	var order []string
+	for _, info := range fileInfos {
+		if info.Hash == "" || info.ContentOmitted {
+			continue
+		}
+		group, ok := byHash[info.Hash]
+		if !ok {
+			group = &DuplicateGroup{Hash: info.Hash, Size: info.Size}
+			byHash[info.Hash] = group
+			order = append(order, info.Hash)
+		}
+		group.Paths = append(group.Paths, info.RelativePath)
+	}
+
+	var groups []DuplicateGroup
+	for _, hash := range order {
+		if group := byHash[hash]; len(group.Paths) > 1 {
+			sort.Strings(group.Paths)
+			groups = append(groups, *group)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Wasted() > groups[j].Wasted()
+	})
+	return groups
+}
+
+// printDuplicates lists each duplicate group under the summary.
+func printDuplicates(groups []DuplicateGroup) {
+	if len(groups) == 0 {
+		fmt.Printf("\n%s No duplicate files found\n", green("✓"))
+		return
+	}
+	fmt.Printf("\n%s Duplicate files:\n", yellow("⚠"))
+	for _, group := range groups {
+		fmt.Printf("  %d copies of %s (%s wasted):\n", len(group.Paths), formatBytes(group.Size), formatBytes(group.Wasted()))
+		for _, path := range group.Paths {
+			fmt.Printf("    %s\n", path)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// editorConfigName is the file -respect-editorconfig reads its rules from.
+const editorConfigName = ".editorconfig"
+
+// editorConfigSection is one [glob] section of an .editorconfig file.
+type editorConfigSection struct {
+	pattern    *regexp.Regexp
+	properties map[string]string
+}
+
+// editorConfigFile is a parsed .editorconfig file.
+type editorConfigFile struct {
+	dir      string
+	root     bool
+	sections []editorConfigSection
+}
+
+// editorConfigCache parses each .editorconfig once per run; files are looked
+// up concurrently when -parallel is set.
+type editorConfigCache struct {
+	open  func(path string) (fs.File, error)
+	mu    sync.Mutex
+	files map[string]*editorConfigFile // by directory; nil if there is none
+}
+
+// newEditorConfigCache returns a cache whose files are read with open.
+func newEditorConfigCache(open func(path string) (fs.File, error)) *editorConfigCache {
+	return &editorConfigCache{open: open, files: make(map[string]*editorConfigFile)}
+}
+
+// load returns the parsed .editorconfig in dir, or nil if dir has none.
+func (c *editorConfigCache) load(dir string) *editorConfigFile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ec, ok := c.files[dir]; ok {
+		return ec
+	}
+	ec, err := parseEditorConfig(filepath.Join(dir, editorConfigName), c.open)
+	if err != nil {
+		ec = nil
+	}
+	c.files[dir] = ec
+	return ec
+}
+
+// properties resolves the editorconfig properties that apply to path: files
+// nearer to it override farther ones, and later sections override earlier
+// ones. The search stops at a file marked root = true.
+func (c *editorConfigCache) properties(path string) map[string]string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+
+	var chain []*editorConfigFile
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		if ec := c.load(dir); ec != nil {
+			chain = append(chain, ec)
+			if ec.root {
+				break
+			}
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+
+	props := make(map[string]string)
+	for i := len(chain) - 1; i >= 0; i-- {
+		ec := chain[i]
+		rel, err := filepath.Rel(ec.dir, abs)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, section := range ec.sections {
+			if section.pattern.MatchString(rel) {
+				for k, v := range section.properties {
+					props[k] = v
+				}
+			}
+		}
+	}
+	return props
+}
+
+// parseEditorConfig reads an .editorconfig file, opened with open. Sections
+// whose glob cannot be translated are ignored rather than failing the run.
+func parseEditorConfig(path string, open func(path string) (fs.File, error)) (*editorConfigFile, error) {
+	f, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ec := &editorConfigFile{dir: filepath.Dir(path)}
+	var current *editorConfigSection
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			current = nil
+			if re, err := regexp.Compile(editorConfigGlob(line[1 : len(line)-1])); err == nil {
+				ec.sections = append(ec.sections, editorConfigSection{pattern: re, properties: make(map[string]string)})
+				current = &ec.sections[len(ec.sections)-1]
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+		if current == nil {
+			if key == "root" {
+				ec.root = value == "true"
+			}
+			continue
+		}
+		current.properties[key] = value
+	}
+	return ec, scanner.Err()
+}
+
+// editorConfigGlob translates an editorconfig section glob into an anchored
+// regular expression over slash-separated paths relative to the file's
+// directory. Globs without a slash match a file name at any depth.
+func editorConfigGlob(glob string) string {
+	if strings.Contains(glob, "/") {
+		glob = strings.TrimPrefix(glob, "/")
+	} else {
+		glob = "**/" + glob
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" matches zero or more directories
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		case '{':
+			end := strings.IndexByte(glob[i:], '}')
+			if end >= 0 {
+				if lo, hi, ok := numericRange(glob[i+1 : i+end]); ok {
+					b.WriteString(numericRangePattern(lo, hi))
+					i += end
+					continue
+				}
+			}
+			braces++
+			b.WriteString("(?:")
+		case '}':
+			if braces > 0 {
+				braces--
+				b.WriteString(")")
+			} else {
+				b.WriteString(`\}`)
+			}
+		case ',':
+			if braces > 0 {
+				b.WriteString("|")
+			} else {
+				b.WriteString(",")
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// numericRange parses the "n1..n2" form of an editorconfig brace expression.
+func numericRange(s string) (int, int, bool) {
+	a, b, ok := strings.Cut(s, "..")
+	if !ok {
+		return 0, 0, false
+	}
+	lo, err1 := strconv.Atoi(a)
+	hi, err2 := strconv.Atoi(b)
+	if err1 != nil || err2 != nil || lo > hi {
+		return 0, 0, false
+	}
+	return lo, hi, true
+}
+
+// numericRangePattern matches any integer in [lo, hi] by listing them; ranges
+// in real .editorconfig files are small.
+func numericRangePattern(lo, hi int) string {
+	const maxAlternatives = 1000
+	if hi-lo > maxAlternatives {
+		return `-?[0-9]+`
+	}
+	values := make([]string, 0, hi-lo+1)
+	for n := lo; n <= hi; n++ {
+		values = append(values, strconv.Itoa(n))
+	}
+	return "(?:" + strings.Join(values, "|") + ")"
+}
+
+// applyEditorConfig normalizes content according to the editorconfig
+// properties that apply to it: indent_style (with indent_size/tab_width),
+// trim_trailing_whitespace and insert_final_newline.
+func applyEditorConfig(content string, props map[string]string) string {
+	if len(props) == 0 || content == "" {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	tabWidth := editorConfigTabWidth(props)
+	for i, line := range lines {
+		cr := strings.HasSuffix(line, "\r")
+		line = strings.TrimSuffix(line, "\r")
+		switch props["indent_style"] {
+		case "space":
+			line = expandIndent(line, tabWidth)
+		case "tab":
+			line = tabifyIndent(line, tabWidth)
+		}
+		if props["trim_trailing_whitespace"] == "true" {
+			line = strings.TrimRight(line, " \t")
+		}
+		if cr {
+			line += "\r"
+		}
+		lines[i] = line
+	}
+	content = strings.Join(lines, "\n")
+
+	switch props["insert_final_newline"] {
+	case "true":
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+	case "false":
+		content = strings.TrimRight(content, "\r\n")
+	}
+	return content
+}
+
+// editorConfigTabWidth is the width of one indentation level: tab_width,
+// falling back to a numeric indent_size, then 4.
+func editorConfigTabWidth(props map[string]string) int {
+	for _, key := range []string{"tab_width", "indent_size"} {
+		if n, err := strconv.Atoi(props[key]); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 4
+}
+
+// expandIndent replaces tabs in the leading whitespace of line with spaces.
+func expandIndent(line string, tabWidth int) string {
+	width, end := indentWidth(line, tabWidth)
+	if !strings.Contains(line[:end], "\t") {
+		return line
+	}
+	return strings.Repeat(" ", width) + line[end:]
+}
+
+// tabifyIndent rewrites the leading whitespace of line as tabs, keeping any
+// remainder narrower than a tab as spaces.
+func tabifyIndent(line string, tabWidth int) string {
+	width, end := indentWidth(line, tabWidth)
+	if !strings.Contains(line[:end], " ") {
+		return line
+	}
+	return strings.Repeat("\t", width/tabWidth) + strings.Repeat(" ", width%tabWidth) + line[end:]
+}
+
+// indentWidth returns the visual width of line's leading whitespace and the
+// byte offset where it ends.
+func indentWidth(line string, tabWidth int) (int, int) {
+	width := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			width++
+		case '\t':
+			width += tabWidth - width%tabWidth
+		default:
+			return width, i
+		}
+	}
+	return width, len(line)
+}
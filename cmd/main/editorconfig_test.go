@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestEditorConfigGlob(t *testing.T) {
+	for _, tc := range []struct {
+		glob    string
+		match   []string
+		noMatch []string
+	}{
+		{"*", []string{"a.go", "x/y/a.go"}, nil},
+		{"*.go", []string{"a.go", "x/a.go"}, []string{"a.gox"}},
+		{"src/*.go", []string{"src/a.go"}, []string{"x/src/a.go", "src/x/a.go"}},
+		{"/src/*.go", []string{"src/a.go"}, []string{"x/src/a.go"}},
+		{"src/**.go", []string{"src/a.go", "src/x/y/a.go"}, []string{"a.go"}},
+		{"**/test/*", []string{"test/a", "x/test/a"}, []string{"test/x/a"}},
+		{"?.md", []string{"a.md"}, []string{"ab.md", "x/.md/"}},
+		{"[abc].txt", []string{"b.txt"}, []string{"d.txt"}},
+		{"[!abc].txt", []string{"d.txt"}, []string{"a.txt"}},
+		{"*.{js,ts}", []string{"a.js", "x/a.ts"}, []string{"a.jsx", "a.{js,ts}"}},
+		{"{Makefile,*.mk}", []string{"Makefile", "x/rules.mk"}, []string{"makefile"}},
+		{"file{1..3}.txt", []string{"file1.txt", "file3.txt"}, []string{"file0.txt", "file4.txt", "file10.txt"}},
+		{"file{-2..2}.txt", []string{"file-2.txt", "file0.txt"}, []string{"file-3.txt"}},
+		{`\*.txt`, []string{"*.txt"}, []string{"a.txt"}},
+		{"a,b", []string{"a,b"}, []string{"a", "b"}},
+	} {
+		re, err := regexp.Compile(editorConfigGlob(tc.glob))
+		if err != nil {
+			t.Errorf("editorConfigGlob(%q): %v", tc.glob, err)
+			continue
+		}
+		for _, path := range tc.match {
+			if !re.MatchString(path) {
+				t.Errorf("%q does not match %q", tc.glob, path)
+			}
+		}
+		for _, path := range tc.noMatch {
+			if re.MatchString(path) {
+				t.Errorf("%q matches %q", tc.glob, path)
+			}
+		}
+	}
+}
+
+func TestApplyEditorConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		props   map[string]string
+		content string
+		want    string
+	}{
+		{"no properties", nil, "\tx  \n", "\tx  \n"},
+		{"spaces", map[string]string{"indent_style": "space", "indent_size": "2"}, "\tx\n\t\ty\n", "  x\n    y\n"},
+		{"spaces default width", map[string]string{"indent_style": "space"}, "\tx\n", "    x\n"},
+		{"tab_width over indent_size", map[string]string{"indent_style": "space", "indent_size": "2", "tab_width": "8"}, "\tx\n", "        x\n"},
+		{"mixed indent to spaces", map[string]string{"indent_style": "space", "indent_size": "4"}, "  \tx\n", "    x\n"},
+		{"tabs", map[string]string{"indent_style": "tab", "indent_size": "4"}, "    x\n      y\n", "\tx\n\t  y\n"},
+		{"tabs leave inner spaces", map[string]string{"indent_style": "tab", "indent_size": "2"}, "  a  b\n", "\ta  b\n"},
+		{"trim", map[string]string{"trim_trailing_whitespace": "true"}, "a \t\nb  \r\nc", "a\nb\r\nc"},
+		{"final newline added", map[string]string{"insert_final_newline": "true"}, "a", "a\n"},
+		{"final newline kept", map[string]string{"insert_final_newline": "true"}, "a\n", "a\n"},
+		{"final newline removed", map[string]string{"insert_final_newline": "false"}, "a\r\n\n", "a"},
+		{"crlf kept", map[string]string{"indent_style": "space", "indent_size": "2"}, "\tx\r\n", "  x\r\n"},
+	} {
+		if got := applyEditorConfig(tc.content, tc.props); got != tc.want {
+			t.Errorf("%s: applyEditorConfig(%q) = %q, want %q", tc.name, tc.content, got, tc.want)
+		}
+	}
+}
+
+// TestEditorConfigProperties checks that nearer files and later sections
+// win, and that a root file ends the search.
+func TestEditorConfigProperties(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".editorconfig":          "[*]\nindent_style = tab\ntrim_trailing_whitespace = true\n",
+		"proj/.editorconfig":     "root = true\n[*]\nindent_style = space\n[*.go]\nindent_style = tab\n",
+		"proj/sub/.editorconfig": "[*.md]\nindent_size = 2\n",
+	})
+	cache := newEditorConfigCache(func(path string) (fs.File, error) { return os.Open(path) })
+
+	for _, tc := range []struct {
+		path string
+		want map[string]string
+	}{
+		{"a.txt", map[string]string{"indent_style": "tab", "trim_trailing_whitespace": "true"}},
+		{"proj/a.txt", map[string]string{"indent_style": "space"}},
+		{"proj/a.go", map[string]string{"indent_style": "tab"}},
+		{"proj/sub/a.md", map[string]string{"indent_style": "space", "indent_size": "2"}},
+	} {
+		got := cache.properties(filepath.Join(dir, filepath.FromSlash(tc.path)))
+		for key, want := range tc.want {
+			if got[key] != want {
+				t.Errorf("%s: %s = %q, want %q", tc.path, key, got[key], want)
+			}
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: properties = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+}
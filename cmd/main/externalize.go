@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// externalizeContent moves each file's content into dir as a blob named by
+// the SHA-256 of that content, and replaces it with a ContentRef to the blob.
+// Identical contents share one blob, and blobs left by earlier runs are
+// reused. It returns the number of distinct blobs the files refer to.
+func externalizeContent(fileInfos []FileInfo, config Config) (int, error) {
+	dir := config.ExternalizeContent
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+
+	blobs := make(map[string]bool)
+	for i := range fileInfos {
+		info := &fileInfos[i]
+		if info.ContentOmitted || info.LinkTarget != "" {
+			continue
+		}
+		// The key is what is stored, after transforms and redaction, not
+		// the file on disk
+		sum := sha256.Sum256([]byte(info.Content))
+		name := hex.EncodeToString(sum[:])
+		blob := filepath.Join(dir, name)
+		if !blobs[name] {
+			if err := writeBlob(blob, info.Content); err != nil {
+				return len(blobs), err
+			}
+			blobs[name] = true
+		}
+		info.ContentRef = contentRef(blob, config)
+		info.Content = ""
+	}
+	return len(blobs), nil
+}
+
+// writeBlob stores content at path unless a blob is already there. Blobs are
+// named by their content, so an existing one never needs rewriting; a
+// temporary file keeps a reader from seeing a partial blob.
+func writeBlob(path, content string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pecel-blob-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// contentRef is the path recorded for a blob: relative to the output file's
+// directory, so the index and its blobs can be moved together, or as given
+// when the output is remote.
+func contentRef(blob string, config Config) string {
+	if !isRemoteOutput(config.OutputFile) {
+		if rel, err := filepath.Rel(filepath.Dir(config.OutputFile), blob); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(blob)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// filterPreviewSample is how many kept and excluded files the filter preview
+// lists of each.
+const filterPreviewSample = 10
+
+// clearAnswer clears a pattern in the filter test; an empty answer keeps it.
+const clearAnswer = "-"
+
+// previewFilters walks config.InputDir and prints how many files the current
+// filters keep and exclude, with a sample of each. Files are judged by
+// shouldProcessFile, exactly as a real run would.
+func previewFilters(config Config, excludeRegex, includeRegex *regexp.Regexp) {
+	var kept, excluded []string
+	walkInput(config.InputDir, config, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != config.InputDir && config.ExcludeHidden && isHidden(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath := getRelativePath(path, config.InputDir)
+		if shouldProcessFile(path, info, config, excludeRegex, includeRegex) {
+			kept = append(kept, relPath)
+		} else {
+			excluded = append(excluded, relPath)
+		}
+		return nil
+	})
+
+	printSample := func(label string, paths []string, mark string) {
+		fmt.Printf("\n%s %s: %d\n", cyan("→"), label, len(paths))
+		for i, path := range paths {
+			if i == filterPreviewSample {
+				fmt.Printf("    ... and %d more\n", len(paths)-filterPreviewSample)
+				break
+			}
+			fmt.Printf("  %s %s\n", mark, path)
+		}
+	}
+	printSample("Kept", kept, green("+"))
+	printSample("Excluded", excluded, red("-"))
+}
+
+// compileFilters compiles the exclude and include patterns, either of which
+// may be empty.
+func compileFilters(exclude, include string) (excludeRegex, includeRegex *regexp.Regexp, err error) {
+	if exclude != "" {
+		if excludeRegex, err = regexp.Compile(exclude); err != nil {
+			return nil, nil, fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+	}
+	if include != "" {
+		if includeRegex, err = regexp.Compile(include); err != nil {
+			return nil, nil, fmt.Errorf("invalid include pattern: %w", err)
+		}
+	}
+	return excludeRegex, includeRegex, nil
+}
+
+// runFilterTest previews config's exclude and include patterns against the
+// input directory and lets the user edit them until the result looks right.
+// It stores the accepted patterns in config and returns them compiled.
+func runFilterTest(config *Config) (excludeRegex, includeRegex *regexp.Regexp) {
+	fmt.Printf("\n%s Filter test: edit the patterns until the preview looks right\n", cyan("→"))
+	for {
+		var err error
+		excludeRegex, includeRegex, err = compileFilters(config.ExcludePattern, config.IncludePattern)
+		if err != nil {
+			fmt.Printf("%s %v\n", red("✗"), err)
+		} else {
+			previewFilters(*config, excludeRegex, includeRegex)
+			fmt.Println()
+			adjust, _ := promptBool("Adjust the patterns", false)
+			if !adjust {
+				return excludeRegex, includeRegex
+			}
+		}
+
+		fmt.Printf("%s Press Enter to keep a pattern, or enter %q to clear it\n", cyan("→"), clearAnswer)
+		config.ExcludePattern = askPattern("Exclude pattern", config.ExcludePattern)
+		config.IncludePattern = askPattern("Include pattern", config.IncludePattern)
+	}
+}
+
+// askPattern prompts for a new value of a pattern in the filter test.
+func askPattern(prompt, current string) string {
+	value, err := promptUser(prompt, current)
+	if err != nil {
+		return current
+	}
+	if value == clearAnswer {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// insideGitRepo reports whether dir is inside a git work tree and git is
+// available to ask.
+func insideGitRepo(dir string) bool {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// gitLastCommit returns the author and date of the last commit that touched
+// path, with the date in the same layout as FileInfo.Modified. Both are empty
+// for untracked files or when git fails.
+func gitLastCommit(path string) (author, date string) {
+	out, err := exec.Command("git", "-C", filepath.Dir(path), "log", "-1",
+		"--format=%an%x00%ad", "--date=format:%Y-%m-%d %H:%M:%S",
+		"--", filepath.Base(path)).Output()
+	if err != nil {
+		return "", ""
+	}
+	author, date, _ = strings.Cut(strings.TrimSpace(string(out)), "\x00")
+	return author, date
+}
+
+// gitAuthorLabel describes the last commit for section headers, or returns ""
+// when the file has none.
+func gitAuthorLabel(info FileInfo) string {
+	if info.LastAuthor == "" {
+		return ""
+	}
+	return info.LastAuthor + " (" + info.LastCommitDate + ")"
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// gitignoreName is the file -gitignore reads its rules from.
+const gitignoreName = ".gitignore"
+
+// gitignoreRule is one pattern line of a .gitignore file.
+type gitignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool // "!pattern" re-includes what earlier rules ignored
+	dirOnly bool // "pattern/" only matches directories
+}
+
+// gitignoreFile is a parsed .gitignore file.
+type gitignoreFile struct {
+	dir   string
+	rules []gitignoreRule
+}
+
+// gitignoreCache parses each .gitignore under the input directory once per
+// run; lookups come from concurrent walkers under -walk-parallel.
+type gitignoreCache struct {
+	root    string
+	open    func(path string) (fs.File, error)
+	verbose bool // report rules that cannot be parsed
+	mu      sync.Mutex
+	files   map[string]*gitignoreFile // by directory; nil if there is none
+}
+
+// newGitignoreCache returns a cache for the tree at root whose files are
+// read with open. With verbose, rules that cannot be parsed are reported
+// as they are dropped.
+func newGitignoreCache(root string, open func(path string) (fs.File, error), verbose bool) *gitignoreCache {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &gitignoreCache{root: root, open: open, verbose: verbose, files: make(map[string]*gitignoreFile)}
+}
+
+// load returns the parsed .gitignore in dir, or nil if dir has none.
+func (c *gitignoreCache) load(dir string) *gitignoreFile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gi, ok := c.files[dir]; ok {
+		return gi
+	}
+	path := filepath.Join(dir, gitignoreName)
+	gi, dropped, err := parseGitignore(path, c.open)
+	if err != nil {
+		gi = nil
+	}
+	if c.verbose {
+		for _, d := range dropped {
+			fmt.Printf("%s Ignoring rule %q in %s:%d: %v\n", yellow("⚠"), d.rule, path, d.line, d.err)
+		}
+	}
+	c.files[dir] = gi
+	return gi
+}
+
+// ignored reports whether path is ignored by the .gitignore files in the
+// input directory and the directories between it and path. As in git, rules
+// in deeper files override shallower ones, and within a file the last
+// matching rule decides. Files under an ignored directory are never reached,
+// because the walk skips the directory itself.
+func (c *gitignoreCache) ignored(path string, isDir bool) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil || abs == c.root {
+		return false
+	}
+	rel, err := filepath.Rel(c.root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	// Directories from the root down to path's parent
+	dirs := []string{c.root}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for _, part := range parts[:len(parts)-1] {
+		dirs = append(dirs, filepath.Join(dirs[len(dirs)-1], part))
+	}
+
+	ignored := false
+	for _, dir := range dirs {
+		gi := c.load(dir)
+		if gi == nil {
+			continue
+		}
+		relToFile, err := filepath.Rel(gi.dir, abs)
+		if err != nil {
+			continue
+		}
+		relToFile = filepath.ToSlash(relToFile)
+		for _, rule := range gi.rules {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.pattern.MatchString(relToFile) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// droppedGitignoreRule is a .gitignore line parseGitignore could not use.
+type droppedGitignoreRule struct {
+	line int
+	rule string
+	err  error
+}
+
+// parseGitignore reads a .gitignore file, opened with open. Rules whose
+// glob cannot be translated are returned as dropped rather than failing the
+// run, as git skips them too.
+func parseGitignore(path string, open func(path string) (fs.File, error)) (*gitignoreFile, []droppedGitignoreRule, error) {
+	f, err := open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	gi := &gitignoreFile{dir: filepath.Dir(path)}
+	var dropped []droppedGitignoreRule
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		// Trailing spaces are dropped unless escaped with a backslash
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+			line = line[:len(line)-1]
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		var rule gitignoreRule
+		glob := line
+		if glob[0] == '!' {
+			rule.negate = true
+			glob = glob[1:]
+		}
+		if strings.HasSuffix(glob, "/") {
+			rule.dirOnly = true
+			glob = strings.TrimSuffix(glob, "/")
+		}
+		if glob == "" {
+			continue
+		}
+		re, err := gitignorePattern(glob)
+		if err != nil {
+			dropped = append(dropped, droppedGitignoreRule{line: n, rule: line, err: err})
+			continue
+		}
+		rule.pattern = re
+		gi.rules = append(gi.rules, rule)
+	}
+	return gi, dropped, scanner.Err()
+}
+
+// gitignorePattern compiles a .gitignore glob, without its "!" prefix and
+// trailing slash, into an anchored regular expression over slash-separated
+// paths relative to the file's directory. A glob with a slash at its start
+// or in its middle is relative to that directory; any other matches a name
+// at any depth. Braces are literal, as in git.
+func gitignorePattern(glob string) (*regexp.Regexp, error) {
+	if strings.Contains(glob, "/") {
+		glob = strings.TrimPrefix(glob, "/")
+	} else {
+		glob = "**/" + glob
+	}
+	expr, err := globRegexp(glob)
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile("^" + expr + "$")
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitignoreIgnored(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore": strings.Join([]string{
+			"*.log",
+			"!keep.log",
+			"build/",
+			"/root.txt",
+			"docs/gen/",
+			"{a,b}.txt",
+			"[!x]y.tmp",
+			"**/cache",
+			"out/**",
+			`\#hash`,
+		}, "\n"),
+		"sub/.gitignore": "!*.log\nlocal.txt\n",
+	})
+
+	cache := newGitignoreCache(dir, func(path string) (fs.File, error) { return os.Open(path) }, false)
+	for _, tc := range []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.log", false, true},
+		{"x/a.log", false, true},
+		{"keep.log", false, false},
+		{"x/keep.log", false, false},
+		// dir-only rules skip files of that name
+		{"build", true, true},
+		{"x/build", true, true},
+		{"build", false, false},
+		// a leading slash anchors to the .gitignore directory
+		{"root.txt", false, true},
+		{"x/root.txt", false, false},
+		// so does a middle slash, even with a trailing one
+		{"docs/gen", true, true},
+		{"x/docs/gen", true, false},
+		{"docs/gen", false, false},
+		// braces are literal
+		{"{a,b}.txt", false, true},
+		{"a.txt", false, false},
+		{"ay.tmp", false, true},
+		{"xy.tmp", false, false},
+		{"cache", true, true},
+		{"x/y/cache", false, true},
+		{"out/a/b", false, true},
+		{"#hash", false, true},
+		// a deeper .gitignore overrides a shallower one
+		{"sub/a.log", false, false},
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false},
+	} {
+		if got := cache.ignored(filepath.Join(dir, filepath.FromSlash(tc.path)), tc.isDir); got != tc.want {
+			t.Errorf("ignored(%s, dir=%v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+}
+
+func TestParseGitignoreDropsMalformedRules(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{".gitignore": "*.log\n[ab\n*.tmp\n"})
+
+	gi, dropped, err := parseGitignore(filepath.Join(dir, ".gitignore"), func(path string) (fs.File, error) { return os.Open(path) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gi.rules) != 2 {
+		t.Errorf("got %d rules, want 2", len(gi.rules))
+	}
+	if len(dropped) != 1 || dropped[0].line != 2 || dropped[0].rule != "[ab" {
+		t.Errorf("dropped = %+v, want line 2 %q", dropped, "[ab")
+	}
+}
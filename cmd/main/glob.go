@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// globRegexp translates a path glob into an unanchored regular expression
+// over slash-separated paths. "*" and "?" never match a slash, "[...]" is a
+// character class negated by a leading "!" or "^", and "\" quotes the next
+// character. "**" as a whole path segment spans directories: "**/" matches
+// zero or more of them and a trailing "/**" everything below; anywhere else
+// it is a plain "*". Braces have no special meaning.
+func globRegexp(glob string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '\\':
+			if i+1 == len(glob) {
+				return "", fmt.Errorf("trailing backslash")
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '*':
+			end := i
+			for end < len(glob) && glob[end] == '*' {
+				end++
+			}
+			wholeSegment := (i == 0 || glob[i-1] == '/') && (end == len(glob) || glob[end] == '/')
+			switch {
+			case end-i == 2 && wholeSegment && end == len(glob):
+				b.WriteString(".*")
+			case end-i == 2 && wholeSegment:
+				// "**/" matches zero or more directories
+				b.WriteString("(?:.*/)?")
+				end++
+			default:
+				b.WriteString("[^/]*")
+			}
+			i = end - 1
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			class, n, err := globClass(glob[i:])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(class)
+			i += n - 1
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String(), nil
+}
+
+// globClass translates the "[...]" character class glob starts with and
+// returns it with the number of bytes it took. A "]" right after the
+// opening bracket is a literal one, and a negated class never matches a
+// slash, as "*" does not.
+func globClass(glob string) (string, int, error) {
+	var b strings.Builder
+	b.WriteString("[")
+	i := 1
+	negated := i < len(glob) && (glob[i] == '!' || glob[i] == '^')
+	if negated {
+		b.WriteString("^")
+		i++
+	}
+	for start := i; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == ']' && i > start:
+			if negated {
+				b.WriteString("/")
+			}
+			b.WriteString("]")
+			return b.String(), i + 1, nil
+		case c == '\\':
+			if i+1 == len(glob) {
+				return "", 0, fmt.Errorf("trailing backslash")
+			}
+			i++
+			b.WriteString(classByte(glob[i]))
+		case c == '-' && i > start && i+1 < len(glob) && glob[i+1] != ']':
+			b.WriteString("-")
+		default:
+			b.WriteString(classByte(c))
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated character class %q", glob)
+}
+
+// classByte quotes c for use inside a regular expression character class.
+func classByte(c byte) string {
+	if c < 0x80 && !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+		return `\` + string(c)
+	}
+	return string([]byte{c})
+}
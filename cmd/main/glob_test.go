@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompileGlobs(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{"*.go", []string{"a.go", "pkg/a.go", "pkg/sub/a.go"}, []string{"a.go.txt", "go"}},
+		{"pkg/*.go", []string{"pkg/a.go"}, []string{"a.go", "pkg/sub/a.go", "x/pkg/a.go"}},
+		{"/pkg/*.go", []string{"pkg/a.go"}, []string{"x/pkg/a.go"}},
+		{"**/testdata/*", []string{"testdata/a", "x/y/testdata/a"}, []string{"testdata/a/b"}},
+		{"docs/**", []string{"docs/a.md", "docs/x/y.md"}, []string{"docs", "a/docs/b.md"}},
+		{"a/**/b.txt", []string{"a/b.txt", "a/x/b.txt", "a/x/y/b.txt"}, []string{"ab.txt", "x/a/b.txt"}},
+		{"a**.txt", []string{"a.txt", "abc.txt", "x/ab.txt"}, []string{"a/b.txt"}},
+		{"?.go", []string{"a.go", "x/b.go"}, []string{"ab.go", ".go"}},
+		{"[ab].go", []string{"a.go", "b.go"}, []string{"c.go"}},
+		{"[!ab].go", []string{"c.go"}, []string{"a.go", "b.go"}},
+		{"[^ab].go", []string{"c.go"}, []string{"a.go"}},
+		{"[a-c]x", []string{"bx"}, []string{"dx"}},
+		{"x[!a]y", []string{"xby"}, []string{"x/y", "xay"}},
+		{"*.{go,md}", []string{"a.{go,md}"}, []string{"a.go", "a.md"}},
+		{`\*.go`, []string{"*.go"}, []string{"a.go"}},
+		{"a+b(c).go", []string{"a+b(c).go"}, []string{"aab(c).go"}},
+	} {
+		re, err := compileGlobs([]string{tc.pattern})
+		if err != nil {
+			t.Errorf("compileGlobs(%q): %v", tc.pattern, err)
+			continue
+		}
+		for _, path := range tc.match {
+			if !re.MatchString(path) {
+				t.Errorf("%q does not match %q", tc.pattern, path)
+			}
+		}
+		for _, path := range tc.noMatch {
+			if re.MatchString(path) {
+				t.Errorf("%q matches %q", tc.pattern, path)
+			}
+		}
+	}
+}
+
+func TestCompileGlobsErrors(t *testing.T) {
+	for _, pattern := range []string{"[ab", "a[", `a\`, "[z-a]"} {
+		_, err := compileGlobs([]string{"*.go", pattern})
+		if err == nil {
+			t.Errorf("compileGlobs(%q) succeeded", pattern)
+		} else if !strings.HasPrefix(err.Error(), fmt.Sprintf("%q: ", pattern)) {
+			t.Errorf("compileGlobs(%q) error %q does not name the pattern", pattern, err)
+		}
+	}
+	if _, err := compileGlobs([]string{" ", ""}); err == nil {
+		t.Error("compileGlobs with no patterns succeeded")
+	}
+}
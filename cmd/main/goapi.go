@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// isGoSource reports whether -go-api-only applies to path.
+func isGoSource(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".go")
+}
+
+// goAPISurface reduces Go source to its exported API: the package clause and
+// the exported constants, variables, types, functions and methods on
+// exported types, with their doc comments. Function bodies, imports,
+// unexported struct fields and interface methods are dropped.
+func goAPISurface(src string) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+	comments := ast.NewCommentMap(fset, file, file.Comments)
+
+	decls := file.Decls[:0]
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+		case *ast.FuncDecl:
+			if d.Recv != nil && !ast.IsExported(receiverType(d.Recv)) {
+				continue
+			}
+			d.Body = nil
+		}
+		decls = append(decls, decl)
+	}
+	file.Decls = decls
+	file.Imports = nil
+	// FileExports drops the unexported names, including struct fields and
+	// interface methods; the comment map then keeps only what is left
+	ast.FileExports(file)
+	file.Comments = comments.Filter(file).Comments()
+
+	var b bytes.Buffer
+	if err := format.Node(&b, fset, file); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// receiverType returns the name of a method receiver's base type, without
+// any pointer or type parameters.
+func receiverType(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	expr := recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
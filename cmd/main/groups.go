@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// rootGroup is the group of files that sit directly in the input directory.
+const rootGroup = "."
+
+// DirGroup totals the files under one top-level directory of the input.
+type DirGroup struct {
+	Dir   string `json:"dir"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// topLevelDir returns the immediate child of the input directory that path
+// lies under, or rootGroup for files directly in it.
+func topLevelDir(path string, config Config) string {
+	rel := filepath.ToSlash(getRelativePath(path, config.InputDir))
+	dir, _, found := strings.Cut(rel, "/")
+	if !found {
+		return rootGroup
+	}
+	return dir
+}
+
+// addToGroups counts info toward its top-level directory's group.
+func addToGroups(groups []DirGroup, info FileInfo, config Config) []DirGroup {
+	dir := topLevelDir(info.Path, config)
+	for i := range groups {
+		if groups[i].Dir == dir {
+			groups[i].Files++
+			groups[i].Bytes += info.Size
+			return groups
+		}
+	}
+	return append(groups, DirGroup{Dir: dir, Files: 1, Bytes: info.Size})
+}
+
+// sortGroups puts the largest groups first, by bytes and then by name.
+func sortGroups(groups []DirGroup) {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Bytes != groups[j].Bytes {
+			return groups[i].Bytes > groups[j].Bytes
+		}
+		return groups[i].Dir < groups[j].Dir
+	})
+}
+
+// groupByTopDir totals fileInfos by top-level directory, largest first.
+func groupByTopDir(fileInfos []FileInfo, config Config) []DirGroup {
+	var groups []DirGroup
+	for _, info := range fileInfos {
+		groups = addToGroups(groups, info, config)
+	}
+	sortGroups(groups)
+	return groups
+}
+
+// printGroups lists the top-level directory totals under the summary.
+func printGroups(groups []DirGroup, totalBytes int64) {
+	if len(groups) == 0 {
+		return
+	}
+	labels := make([]string, len(groups))
+	width := 0
+	for i, group := range groups {
+		labels[i] = group.Dir + "/"
+		if group.Dir == rootGroup {
+			labels[i] = "(top level)"
+		}
+		if len(labels[i]) > width {
+			width = len(labels[i])
+		}
+	}
+
+	fmt.Printf("\n%s Files by top-level directory:\n", cyan("→"))
+	for i, group := range groups {
+		share := 0.0
+		if totalBytes > 0 {
+			share = float64(group.Bytes) / float64(totalBytes) * 100
+		}
+		files := "files"
+		if group.Files == 1 {
+			files = "file"
+		}
+		fmt.Printf("  %-*s  %6d %-5s  %10s  %5.1f%%\n", width, labels[i], group.Files, files, formatBytes(group.Bytes), share)
+	}
+}
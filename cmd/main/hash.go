@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// hashChunkSize is the read size used when hashing large files. Files smaller
+// than one chunk are read and hashed in a single pass.
+const hashChunkSize = 1024 * 1024
+
+// hashFile returns the hex SHA-256 digest of the file at path, streaming it
+// through the hasher so the content is never held in memory. Reads are paced
+// under -read-rate-limit.
+func hashFile(path string, config Config) (string, error) {
+	file, err := openInput(path, config)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if config.readLimiter != nil {
+		r = throttledReader{r: file, limiter: config.readLimiter}
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readAndHash reads the file at path and returns its content with the hex
+// SHA-256 digest. For large files a second goroutine hashes each chunk as soon
+// as it has been read, so hashing overlaps the next read instead of running as
+// a separate pass over the content afterwards.
+func readAndHash(path string, sizeHint int64, config Config) ([]byte, string, error) {
+	if sizeHint < hashChunkSize {
+		content, err := readInputFile(path, sizeHint, config)
+		if err != nil {
+			return nil, "", err
+		}
+		sum := sha256.Sum256(content)
+		return content, hex.EncodeToString(sum[:]), nil
+	}
+
+	file, err := openInput(path, config)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	chunks := make(chan []byte, 4)
+	digest := make(chan string, 1)
+	go func() {
+		h := sha256.New()
+		for chunk := range chunks {
+			h.Write(chunk)
+		}
+		digest <- hex.EncodeToString(h.Sum(nil))
+	}()
+
+	// Chunks are sub-slices of content. Growing content may move it to a new
+	// array, but bytes already handed to the hasher are never written again.
+	content := make([]byte, 0, sizeHint+1)
+	for {
+		if len(content) == cap(content) {
+			content = append(content, 0)[:len(content)]
+		}
+		end := len(content) + hashChunkSize
+		if end > cap(content) {
+			end = cap(content)
+		}
+		n, readErr := file.Read(content[len(content):end])
+		if n > 0 {
+			chunks <- content[len(content) : len(content)+n]
+			content = content[:len(content)+n]
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			close(chunks)
+			<-digest
+			return nil, "", readErr
+		}
+	}
+	close(chunks)
+	return content, <-digest, nil
+}
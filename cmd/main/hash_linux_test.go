@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// teeReadAndHash is the single-pass alternative to readAndHash: the hasher
+// sees each read through an io.TeeReader on the reading goroutine.
+func teeReadAndHash(path string, sizeHint int64) ([]byte, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	content := bytes.NewBuffer(make([]byte, 0, sizeHint+1))
+	if _, err := content.ReadFrom(io.TeeReader(file, h)); err != nil {
+		return nil, "", err
+	}
+	return content.Bytes(), hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// BenchmarkReadAndHash compares readAndHash, which hashes each chunk on a
+// second goroutine while the next one is read, with teeReadAndHash, from the
+// page cache (warm) and from the disk (cold).
+func BenchmarkReadAndHash(b *testing.B) {
+	dir := b.TempDir()
+	for _, size := range []int64{4 << 20, 64 << 20} {
+		path := filepath.Join(dir, fmt.Sprintf("%d.bin", size))
+		if err := os.WriteFile(path, bytes.Repeat([]byte("hashed content\n"), int(size/15)), 0o644); err != nil {
+			b.Fatal(err)
+		}
+		want, _ := os.ReadFile(path)
+		sum := sha256.Sum256(want)
+
+		for _, impl := range []struct {
+			name string
+			read func(string, int64) ([]byte, string, error)
+		}{
+			{"chunked", func(path string, sizeHint int64) ([]byte, string, error) {
+				return readAndHash(path, sizeHint, Config{})
+			}},
+			{"tee", teeReadAndHash},
+		} {
+			for _, cold := range []bool{false, true} {
+				cache := "warm"
+				if cold {
+					cache = "cold"
+				}
+				b.Run(fmt.Sprintf("%s/%s/%dMiB", cache, impl.name, size>>20), func(b *testing.B) {
+					b.SetBytes(int64(len(want)))
+					for i := 0; i < b.N; i++ {
+						if cold {
+							b.StopTimer()
+							dropPageCache(b, []walkEntry{{Path: path}})
+							b.StartTimer()
+						}
+						content, digest, err := impl.read(path, int64(len(want)))
+						if err != nil {
+							b.Fatal(err)
+						}
+						if len(content) != len(want) || digest != hex.EncodeToString(sum[:]) {
+							b.Fatalf("%s read %d bytes with digest %s", impl.name, len(content), digest)
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// defaultHexdumpWidth is the bytes per hex dump line, as in hexdump -C.
+const defaultHexdumpWidth = 16
+
+// encodingHexdump marks FileInfo content rendered by -hexdump-binary.
+const encodingHexdump = "hexdump"
+
+// encodingBase64 marks FileInfo content encoded by -base64-binary.
+const encodingBase64 = "base64"
+
+// base64LineWidth is where -base64-binary wraps its output, as MIME does.
+const base64LineWidth = 76
+
+// setContent stores content on info as it goes into the output: a hex dump
+// for binary files under -hexdump-binary, base64 under -base64-binary,
+// otherwise the text itself.
+func setContent(info *FileInfo, content []byte, config Config) {
+	if config.Base64Binary && isBinaryContent(content, binaryThreshold(config)) {
+		info.Content = base64Lines(content)
+		info.Encoding = encodingBase64
+		return
+	}
+	if config.HexdumpBinary && isBinaryContent(content, binaryThreshold(config)) {
+		width := config.HexdumpWidth
+		if width <= 0 {
+			width = defaultHexdumpWidth
+		}
+		info.Content = hexDump(content, width)
+		info.Encoding = encodingHexdump
+		return
+	}
+	info.Content = string(content)
+	if config.LineCounts {
+		info.LineCount = countLines(content)
+	}
+}
+
+// base64Lines encodes data as standard base64 wrapped at base64LineWidth.
+func base64Lines(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > base64LineWidth {
+		b.WriteString(encoded[:base64LineWidth])
+		b.WriteByte('\n')
+		encoded = encoded[base64LineWidth:]
+	}
+	b.WriteString(encoded)
+	return b.String()
+}
+
+// hexDump renders data like hexdump -C: an offset, width bytes in hex with an
+// extra space after every eighth, and the printable ASCII characters, ending
+// with a line holding the total length.
+func hexDump(data []byte, width int) string {
+	var b strings.Builder
+	pair := make([]byte, 2)
+	for offset := 0; offset < len(data); offset += width {
+		line := data[offset:min(offset+width, len(data))]
+		fmt.Fprintf(&b, "%08x ", offset)
+		for i := 0; i < width; i++ {
+			if i%8 == 0 {
+				b.WriteByte(' ')
+			}
+			if i >= len(line) {
+				b.WriteString("   ")
+				continue
+			}
+			hex.Encode(pair, line[i:i+1])
+			b.Write(pair)
+			b.WriteByte(' ')
+		}
+		b.WriteString(" |")
+		for _, c := range line {
+			if c < 0x20 || c > 0x7e {
+				c = '.'
+			}
+			b.WriteByte(c)
+		}
+		b.WriteString("|\n")
+	}
+	fmt.Fprintf(&b, "%08x\n", len(data))
+	return b.String()
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// htmlAppTemplate renders -format html-app (alias html): one self-contained
+// page with a collapsible file tree in a sidebar, a collapsible <details>
+// section per file and a search box that filters sections by path and
+// content. All CSS and JS is inline so the file works when opened straight
+// from disk.
+var htmlAppTemplate = template.Must(template.New("html-app").Funcs(template.FuncMap{
+	"bytes":      formatBytes,
+	"fileAnchor": fileAnchor,
+}).Parse(`{{define "tree"}}<ul>
+{{range .}}{{if .Anchor}}<li><a href="#{{.Anchor}}">{{.Name}}</a></li>
+{{else}}<li><details open><summary>{{.Name}}/</summary>
+{{template "tree" .Children}}</details></li>
+{{end}}{{end}}</ul>
+{{end}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Pecel Output</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; color: #222; background: #fafafa; display: flex; }
+#tree { position: sticky; top: 0; flex: 0 0 260px; height: 100vh; overflow: auto; box-sizing: border-box; padding: 12px 8px; background: #fff; border-right: 1px solid #ddd; font-family: ui-monospace, monospace; font-size: 0.8em; }
+#tree ul { list-style: none; margin: 0; padding-left: 12px; }
+#tree > ul { padding-left: 0; }
+#tree summary { padding: 2px 0; }
+#tree a { display: block; padding: 2px 0; color: #0550ae; text-decoration: none; white-space: nowrap; overflow: hidden; text-overflow: ellipsis; }
+#tree a:hover { text-decoration: underline; }
+#page { flex: 1; min-width: 0; }
+header { position: sticky; top: 0; background: #fff; border-bottom: 1px solid #ddd; padding: 12px 20px; }
+header h1 { font-size: 1.2em; margin: 0 0 4px; }
+header .meta { color: #666; font-size: 0.85em; }
+#search { width: 100%; box-sizing: border-box; margin-top: 8px; padding: 6px 8px; font-size: 1em; }
+#toolbar { margin-top: 6px; font-size: 0.85em; color: #666; }
+#toolbar button { font-size: 0.85em; margin-left: 6px; }
+main { padding: 12px 20px; }
+details { background: #fff; border: 1px solid #ddd; border-radius: 4px; margin-bottom: 8px; }
+summary { cursor: pointer; padding: 6px 10px; font-family: ui-monospace, monospace; }
+summary .index { color: #888; margin-right: 8px; }
+summary .info { color: #888; font-family: system-ui, sans-serif; font-size: 0.8em; margin-left: 8px; }
+pre { margin: 0; padding: 10px; overflow-x: auto; border-top: 1px solid #eee; font-size: 0.85em; }
+footer { padding: 12px 20px; color: #666; font-size: 0.85em; }
+.hidden { display: none; }
+</style>
+</head>
+<body>
+<nav id="tree">
+{{template "tree" .Tree}}</nav>
+<div id="page">
+<header>
+{{if .Header}}{{.Header}}{{else if not .NoHeader}}<h1>Pecel Output</h1>
+<div class="meta">Generated {{.Generated}} &middot; {{.Stats.FilesProcessed}} files &middot; {{.Stats.Directories}} directories &middot; {{bytes .Stats.TotalBytes}}</div>{{end}}
+<input id="search" type="search" placeholder="Search file names and content" autofocus>
+<div id="toolbar"><span id="count">{{len .Files}} files</span><button id="expand" type="button">Expand all</button><button id="collapse" type="button">Collapse all</button></div>
+</header>
+<main>
+{{range $i, $f := .Files}}{{with $f}}<details id="{{fileAnchor $i}}" data-path="{{.RelativePath}}">
+<summary>{{if .Index}}<span class="index">File {{.Index}} of {{$.TotalFiles}}</span>{{end}}{{.RelativePath}}<span class="info">{{bytes .Size}}{{if and $.LineCounts (not .Encoding) (not .ContentOmitted) (not .LinkTarget)}} &middot; {{.LineCount}} lines{{end}} &middot; {{.Modified}}{{if .DiffStatus}} &middot; {{.DiffStatus}}{{end}}{{if .LinkTarget}} &middot; &rarr; {{.LinkTarget}}{{end}}{{if .LastAuthor}} &middot; {{.LastAuthor}}, {{.LastCommitDate}}{{end}}{{if .ContentOmitted}} &middot; content omitted{{end}}</span></summary>
+<pre><code>{{.Content}}</code></pre>
+</details>
+{{end}}{{end}}</main>
+{{if .Footer}}<footer>{{.Footer}}</footer>
+{{end}}</div>
+<script>
+(function () {
+  var sections = Array.prototype.slice.call(document.querySelectorAll("details"));
+  var search = document.getElementById("search");
+  var count = document.getElementById("count");
+  var timer;
+  function filter() {
+    var q = search.value.toLowerCase();
+    var shown = 0;
+    sections.forEach(function (d) {
+      var match = !q || d.dataset.path.toLowerCase().indexOf(q) >= 0 ||
+        d.querySelector("pre").textContent.toLowerCase().indexOf(q) >= 0;
+      d.classList.toggle("hidden", !match);
+      var link = document.querySelector('#tree a[href="#' + d.id + '"]');
+      if (link) link.parentNode.classList.toggle("hidden", !match);
+      if (match) shown++;
+    });
+    count.textContent = shown + " of " + sections.length + " files";
+  }
+  search.addEventListener("input", function () {
+    clearTimeout(timer);
+    timer = setTimeout(filter, 150);
+  });
+  function setOpen(open) {
+    sections.forEach(function (d) { if (!d.classList.contains("hidden")) d.open = open; });
+  }
+  document.getElementById("expand").addEventListener("click", function () { setOpen(true); });
+  document.getElementById("collapse").addEventListener("click", function () { setOpen(false); });
+  function openTarget() {
+    var d = location.hash && document.getElementById(location.hash.slice(1));
+    if (d && d.tagName === "DETAILS") d.open = true;
+  }
+  window.addEventListener("hashchange", openTarget);
+  openTarget();
+})();
+</script>
+</body>
+</html>
+`))
+
+// writeHTMLAppOutput renders the bundle as a single self-contained HTML page.
+// Custom header and footer templates produce HTML that is inserted as is.
+// -no-header drops the title and run details but keeps the search toolbar.
+func writeHTMLAppOutput(fileInfos []FileInfo, writer io.Writer, config Config, stats Stats) (int64, error) {
+	data := newDocumentData(config, stats)
+	header, err := documentText(config.headerTemplate, data, "")
+	if err != nil {
+		return 0, err
+	}
+	footer, err := documentText(config.footerTemplate, data, "")
+	if err != nil {
+		return 0, err
+	}
+
+	counter := &countingWriter{w: writer}
+	bufWriter := bufio.NewWriter(counter)
+	err = htmlAppTemplate.Execute(bufWriter, struct {
+		Generated  string
+		Stats      Stats
+		Files      []FileInfo
+		Tree       []*htmlTreeNode
+		Header     template.HTML
+		Footer     template.HTML
+		NoHeader   bool
+		TotalFiles int
+		LineCounts bool
+	}{
+		Generated:  data.Generated,
+		Stats:      stats,
+		Files:      fileInfos,
+		Tree:       htmlFileTree(fileInfos),
+		Header:     template.HTML(header),
+		Footer:     template.HTML(footer),
+		NoHeader:   config.NoHeader,
+		TotalFiles: config.totalFiles,
+		LineCounts: config.LineCounts,
+	})
+	if err != nil {
+		return counter.n, err
+	}
+	err = bufWriter.Flush()
+	return counter.n, err
+}
+
+// htmlTreeNode is a directory or file in the html-app sidebar. Files carry
+// the anchor of their section; directories carry their children in walk
+// order.
+type htmlTreeNode struct {
+	Name     string
+	Anchor   string
+	Children []*htmlTreeNode
+}
+
+// fileAnchor is the id of the i-th file's section in the html-app page.
+func fileAnchor(i int) string {
+	return fmt.Sprintf("file-%d", i+1)
+}
+
+// htmlFileTree arranges fileInfos into the sidebar's directory tree.
+func htmlFileTree(fileInfos []FileInfo) []*htmlTreeNode {
+	root := &htmlTreeNode{}
+	dirs := map[string]*htmlTreeNode{"": root}
+	for i, info := range fileInfos {
+		parts := strings.Split(filepath.ToSlash(info.RelativePath), "/")
+		parent, dir := root, ""
+		for _, name := range parts[:len(parts)-1] {
+			dir += name + "/"
+			node, ok := dirs[dir]
+			if !ok {
+				node = &htmlTreeNode{Name: name}
+				parent.Children = append(parent.Children, node)
+				dirs[dir] = node
+			}
+			parent = node
+		}
+		parent.Children = append(parent.Children, &htmlTreeNode{Name: parts[len(parts)-1], Anchor: fileAnchor(i)})
+	}
+	return root.Children
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// inputFS is the tree of an -input that is not on the local disk (sftp://).
+// Paths in the pipeline keep their usual form, InputDir joined with the path
+// below it; the helpers below turn them into the slash-separated names,
+// relative to InputDir, that inputFS serves as io/fs does, and open local
+// files through the extended-length form of a long path.
+type inputFS interface {
+	fs.StatFS
+	fs.ReadDirFS
+	Lstat(name string) (fs.FileInfo, error)
+	ReadLink(name string) (string, error)
+	Close() error
+}
+
+// inputName returns the inputFS name of path. Paths outside the input tree,
+// such as the parents searched for .editorconfig files, do not exist there.
+func inputName(op, path string, config Config) (string, error) {
+	rel, err := filepath.Rel(config.InputDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &fs.PathError{Op: op, Path: path, Err: fs.ErrNotExist}
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// openInput opens a file of the input tree for reading.
+func openInput(path string, config Config) (fs.File, error) {
+	if config.inputFS == nil {
+		return os.Open(extendedPath(path))
+	}
+	name, err := inputName("open", path, config)
+	if err != nil {
+		return nil, err
+	}
+	return config.inputFS.Open(name)
+}
+
+// inputOpener returns openInput bound to config, for code that only needs to
+// open files of the input tree.
+func inputOpener(config Config) func(path string) (fs.File, error) {
+	return func(path string) (fs.File, error) {
+		return openInput(path, config)
+	}
+}
+
+// statInput is os.Stat for the input tree.
+func statInput(path string, config Config) (fs.FileInfo, error) {
+	if config.inputFS == nil {
+		return os.Stat(extendedPath(path))
+	}
+	name, err := inputName("stat", path, config)
+	if err != nil {
+		return nil, err
+	}
+	return config.inputFS.Stat(name)
+}
+
+// lstatInput is os.Lstat for the input tree.
+func lstatInput(path string, config Config) (fs.FileInfo, error) {
+	if config.inputFS == nil {
+		return os.Lstat(path)
+	}
+	name, err := inputName("lstat", path, config)
+	if err != nil {
+		return nil, err
+	}
+	return config.inputFS.Lstat(name)
+}
+
+// readInputLink is os.Readlink for the input tree.
+func readInputLink(path string, config Config) (string, error) {
+	if config.inputFS == nil {
+		return os.Readlink(path)
+	}
+	name, err := inputName("readlink", path, config)
+	if err != nil {
+		return "", err
+	}
+	return config.inputFS.ReadLink(name)
+}
+
+// readInputDir is os.ReadDir for the input tree.
+func readInputDir(dir string, config Config) ([]fs.DirEntry, error) {
+	if config.inputFS == nil {
+		return os.ReadDir(dir)
+	}
+	name, err := inputName("readdir", dir, config)
+	if err != nil {
+		return nil, err
+	}
+	return config.inputFS.ReadDir(name)
+}
+
+// readInputFile reads a whole file of the input tree, no faster than
+// -read-rate-limit allows when it is set. sizeHint is the size the file is
+// expected to have.
+func readInputFile(path string, sizeHint int64, config Config) ([]byte, error) {
+	if config.inputFS == nil && config.readLimiter == nil {
+		return os.ReadFile(extendedPath(path))
+	}
+	file, err := openInput(path, config)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if config.readLimiter != nil {
+		r = throttledReader{r: file, limiter: config.readLimiter}
+	}
+	var buf bytes.Buffer
+	buf.Grow(int(sizeHint) + bytes.MinRead)
+	// io.Copy uses the file's WriteTo when it has one, which for a remote
+	// file keeps several reads in flight
+	_, err = io.Copy(&buf, r)
+	return buf.Bytes(), err
+}
+
+// walkInput is filepath.Walk over the input tree: fn sees the same paths,
+// lstat information and errors for a remote tree as for a local one.
+func walkInput(root string, config Config, fn filepath.WalkFunc) error {
+	if config.inputFS == nil {
+		return filepath.Walk(root, fn)
+	}
+	name, err := inputName("lstat", root, config)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	return fs.WalkDir(config.inputFS, name, func(name string, d fs.DirEntry, err error) error {
+		path := filepath.Join(config.InputDir, filepath.FromSlash(name))
+		if err != nil {
+			var info fs.FileInfo
+			if d != nil {
+				info, _ = d.Info()
+			}
+			return fn(path, info, err)
+		}
+		info, err := d.Info()
+		return fn(path, info, err)
+	})
+}
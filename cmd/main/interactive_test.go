@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestInteractiveAnswersOverrideProjectConfig answers the interactive
+// questions in a directory with a .pecel.json and checks that the answers,
+// not the configuration file, decide the run.
+func TestInteractiveAnswersOverrideProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".pecel.json": `{"output_file": "fromcfg.txt", "extensions": [".md"]}`,
+		"a.go":        "package a\n",
+	})
+
+	// Input directory, output file and extensions are answered, every other
+	// question and the review screen keep their defaults
+	cmd := pecelCommand()
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(".\nmine.txt\n.go\n" + strings.Repeat("\n", 11))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("pecel: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fromcfg.txt")); err == nil {
+		t.Errorf("the run wrote the configuration file's output_file:\n%s", out)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "mine.txt"))
+	if err != nil {
+		t.Fatalf("the answered output file was not written: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(data), "\na.go\n") {
+		t.Errorf("mine.txt does not hold a.go, so the answered extensions were ignored:\n%s", data)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isPathTooLong reports whether err means the operating system rejected a
+// path for its length, so the file is reported as such rather than as a
+// generic read failure.
+func isPathTooLong(err error) bool {
+	return errors.Is(err, syscall.ENAMETOOLONG) || isPlatformPathTooLong(err)
+}
+
+// reportPathTooLong notes a file skipped because its path is too long.
+func reportPathTooLong(path string, config Config) {
+	if !config.Quiet {
+		config.console.printf("%s Skipping %s: path too long (%d characters)\n", yellow("⚠"), path, len(path))
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+func isPlatformPathTooLong(error) bool {
+	return false
+}
+
+// extendedPath returns path unchanged: only Windows has an extended-length
+// path form.
+func extendedPath(path string) string {
+	return path
+}
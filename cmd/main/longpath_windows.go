@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// maxShortPath is the length from which Windows needs the extended-length
+// prefix: MAX_PATH less room for an 8.3 file name, the limit os itself uses.
+const maxShortPath = 248
+
+// errorFilenameExcedRange is ERROR_FILENAME_EXCED_RANGE.
+const errorFilenameExcedRange = syscall.Errno(206)
+
+func isPlatformPathTooLong(err error) bool {
+	return errors.Is(err, errorFilenameExcedRange)
+}
+
+// extendedPath returns the \\?\ form of a long path so opening it is not
+// subject to MAX_PATH. os only does this for absolute paths, and the walk
+// yields paths relative to -input.
+func extendedPath(path string) string {
+	if len(path) < maxShortPath || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
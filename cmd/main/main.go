@@ -3,21 +3,33 @@ package main
 import (
 	"bufio"
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"text/tabwriter"
+	"text/template"
 	"time"
 
+	"github.com/bhangun/pecel/pkg/pecel"
 	"github.com/fatih/color"
+	"github.com/klauspost/pgzip"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -25,20 +37,252 @@ const (
 )
 
 type Config struct {
-	InputDir       string   `json:"input_dir"`
-	OutputFile     string   `json:"output_file"`
-	Extensions     []string `json:"extensions"`
-	ExcludeHidden  bool     `json:"exclude_hidden"`
-	MaxFileSize    int64    `json:"max_file_size"`
-	MinFileSize    int64    `json:"min_file_size"`
-	ExcludePattern string   `json:"exclude_pattern"`
-	IncludePattern string   `json:"include_pattern"`
-	OutputFormat   string   `json:"output_format"`
-	Compress       bool     `json:"compress"`
-	Parallel       int      `json:"parallel"`
-	Quiet          bool     `json:"quiet"`
-	Verbose        bool     `json:"verbose"`
-	DryRun         bool     `json:"dry_run"`
+	InputDir        string   `json:"input_dir"`
+	OutputFile      string   `json:"output_file"`
+	Extensions      []string `json:"extensions"`
+	ExcludeHidden   bool     `json:"exclude_hidden"`
+	MaxFileSize     int64    `json:"max_file_size"`
+	MinFileSize     int64    `json:"min_file_size"`
+	ExcludePattern  string   `json:"exclude_pattern"`
+	IncludePattern  string   `json:"include_pattern"`
+	ExcludeGlobs    []string `json:"exclude_globs"`
+	IncludeGlobs    []string `json:"include_globs"`
+	AllowList       string   `json:"allow_list"`
+	DenyList        string   `json:"deny_list"`
+	OrderFile       string   `json:"order_file"`
+	OutputFormat    string   `json:"output_format"`
+	Compress        bool     `json:"compress"`
+	Parallel        int      `json:"parallel"`
+	WalkParallel    int      `json:"walk_parallel"`
+	ReadOrder       string   `json:"read_order"`
+	ReadRateLimit   int64    `json:"read_rate_limit"`
+	Quiet           bool     `json:"quiet"`
+	Verbose         bool     `json:"verbose"`
+	DryRun          bool     `json:"dry_run"`
+	MarshalWorkers  int      `json:"marshal_workers"`
+	RelativeTime    bool     `json:"relative_time"`
+	NewerThan       string   `json:"newer_than"`
+	OlderThan       string   `json:"older_than"`
+	PlainSummary    bool     `json:"plain_summary"`
+	OutputMtime     string   `json:"output_mtime"`
+	ChunkTokens     int      `json:"chunk_tokens"`
+	Hash            bool     `json:"hash"`
+	DiffAgainst     string   `json:"diff_against"`
+	ExcludeVendored bool     `json:"exclude_vendored"`
+	GitIgnore       bool     `json:"gitignore"`
+	ExcludeTests    bool     `json:"exclude_tests"`
+	DedupByName     string   `json:"dedup_by_name"`
+	PathPrefixLines bool     `json:"path_prefix_lines"`
+	DetectType      bool     `json:"detect_type"`
+	RootLabel       string   `json:"root_label"`
+	// KeepBOM leaves UTF-8 byte order marks in file content.
+	KeepBOM bool `json:"keep_bom"`
+	// KeepLargeBinaries disables the default guard that skips large files
+	// which look binary; LargeBinaryThreshold overrides its size cutoff.
+	KeepLargeBinaries    bool  `json:"keep_large_binaries"`
+	LargeBinaryThreshold int64 `json:"large_binary_threshold"`
+	ExcludeSymlinks      bool  `json:"exclude_symlinks"`
+	// HexdumpBinary renders binary files as a hex dump of HexdumpWidth bytes
+	// per line (16 when unset) instead of their raw bytes.
+	HexdumpBinary bool `json:"hexdump_binary"`
+	HexdumpWidth  int  `json:"hexdump_width"`
+	// BinaryThreshold is the share of non-text bytes above which content
+	// counts as binary; 0 uses the default of 0.3.
+	BinaryThreshold float64 `json:"binary_threshold"`
+	// KeepBinaries turns off the default that leaves out every file that
+	// looks binary; Base64Binary includes them base64-encoded instead.
+	KeepBinaries bool `json:"keep_binaries"`
+	Base64Binary bool `json:"base64_binary"`
+	// FollowSymlinks walks into symlinked directories, skipping cycles and
+	// chains longer than MaxSymlinkDepth hops.
+	FollowSymlinks  bool `json:"follow_symlinks"`
+	MaxSymlinkDepth int  `json:"max_symlink_depth"`
+	// MaterializeSymlinks follows symlinks and includes every link as a copy
+	// of its target under the link's own path, even when the target is also
+	// in the tree. Implies FollowSymlinks.
+	MaterializeSymlinks bool `json:"materialize_symlinks"`
+	// SymlinkMetadata records excluded symlinks and their targets as
+	// content-less entries instead of dropping them.
+	SymlinkMetadata bool `json:"symlink_metadata"`
+	// ContentGrep keeps only files whose content matches; with SnippetLines
+	// set, those files contribute just the lines around each match.
+	ContentGrep  string `json:"content_grep"`
+	SnippetLines int    `json:"snippet_lines"`
+	// OutputDirMirror writes each processed file to its own path under this
+	// directory instead of producing a combined output.
+	OutputDirMirror string `json:"output_dir_mirror"`
+	// ExternalizeContent stores each distinct content in this directory,
+	// named by its hash, and leaves only a content_ref in JSON or XML output.
+	ExternalizeContent string `json:"externalize_content"`
+	// RenameExtensions rewrites mirrored file extensions, as "jsx=js,tsx=ts".
+	RenameExtensions string `json:"rename_extensions"`
+	// HeaderTemplateFile and FooterTemplateFile are Go templates replacing
+	// the built-in document header and footer.
+	HeaderTemplateFile string `json:"header_template_file"`
+	FooterTemplateFile string `json:"footer_template_file"`
+	// NoHeader and NoFooter leave the document header and summary footer out
+	// entirely, so the output is just the file sections.
+	NoHeader bool `json:"no_header"`
+	NoFooter bool `json:"no_footer"`
+	// MaxOutputLines caps text and markdown output, stopping at the last
+	// file that fits.
+	MaxOutputLines      int  `json:"max_output_lines"`
+	RespectEditorConfig bool `json:"respect_editorconfig"`
+	// MarkdownBlockLines splits markdown code blocks longer than this many
+	// lines into continued blocks.
+	MarkdownBlockLines int  `json:"markdown_block_lines"`
+	Watch              bool `json:"watch"`
+	// LangMap overrides markdown fence languages by extension, as
+	// ".vue=html,.tpl=jinja".
+	LangMap string `json:"lang_map"`
+	// MarkdownTOC adds a table of contents linking to each file's section
+	// to markdown output.
+	MarkdownTOC bool `json:"markdown_toc"`
+	// CSVIncludeContent adds a content column to csv output.
+	CSVIncludeContent bool `json:"csv_include_content"`
+	// MaxBlankLines caps runs of consecutive blank lines in content; 0
+	// keeps them all.
+	MaxBlankLines int `json:"max_blank_lines"`
+	// SeparatorWidth is the length of the separator lines in text output;
+	// 0 fits them to the terminal when writing to one and uses 80 otherwise.
+	SeparatorWidth int `json:"separator_width"`
+	// ContentMaxDepth reads content only for files at most this many levels
+	// below the input directory; deeper files are listed without content.
+	ContentMaxDepth int `json:"content_max_depth"`
+	// ProgressFile receives periodic JSON progress updates for other tools.
+	ProgressFile string `json:"progress_file"`
+	// Timeout stops reading after this long and writes the files processed
+	// so far, e.g. "30s".
+	Timeout string `json:"timeout"`
+	// MetricsFile receives the run's stats in Prometheus textfile format.
+	MetricsFile string `json:"metrics_file"`
+	// ReportDuplicates lists groups of identical files after the summary
+	// without removing any of them from the output.
+	ReportDuplicates bool `json:"report_duplicates"`
+	// GroupSummary breaks the summary down by top-level directory.
+	GroupSummary bool `json:"group_summary"`
+	// QuarantineOversize lists the files -max-size leaves out, with their
+	// sizes, instead of only counting them away.
+	QuarantineOversize bool `json:"quarantine_oversize"`
+	// ContentHashOnly streams each file through the hasher without keeping
+	// its content and writes a path to hash map instead of the bundle.
+	ContentHashOnly bool `json:"content_hash_only"`
+	// ResumeParts keeps the -chunk-by-tokens or -split-size parts that the
+	// parts manifest of an earlier, interrupted run shows as complete and
+	// unchanged.
+	ResumeParts bool `json:"resume_parts"`
+	// SplitSize splits the output into parts of about this many bytes
+	// ("10MB"), never splitting a file.
+	SplitSize string `json:"split_size"`
+	// CompactEmptySections renders files with no content left as a single
+	// line instead of a full section.
+	CompactEmptySections bool `json:"compact_empty_sections"`
+	// NumberFiles labels each section "File N of M" and records the
+	// position as Index in JSON and XML.
+	NumberFiles bool `json:"number_files"`
+	// GoAPIOnly reduces .go files to their exported declarations, without
+	// function bodies.
+	GoAPIOnly bool `json:"go_api_only"`
+	// LineCounts records and shows each file's number of lines.
+	LineCounts bool `json:"line_counts"`
+	// JSONPrettyThreshold switches JSON output to compact above a number of
+	// content bytes ("5000000") or files ("200files"); unset always indents.
+	JSONPrettyThreshold string `json:"json_pretty_threshold"`
+	// VanishedFiles says how files removed between the walk and the read are
+	// reported: info (default), ignore or error.
+	VanishedFiles string `json:"vanished_files"`
+	// GitAuthor records who last committed each file, and when.
+	GitAuthor bool `json:"git_author"`
+	// Manifest is a sidecar listing each file's hash; VerifyManifest checks
+	// the tree against such a manifest instead of writing output.
+	Manifest       string `json:"manifest"`
+	VerifyManifest string `json:"verify_against_manifest"`
+	// CompressWorkers is how many goroutines compress large outputs;
+	// 0 follows Parallel.
+	CompressWorkers int `json:"compress_workers"`
+	// Compression is the output codec: none, gzip or zstd. Compress is an
+	// alias for gzip; once resolved, Compress reports whether any codec is
+	// in use.
+	Compression string `json:"compression"`
+	// CompressionLevel is the codec's level; 0 uses its default.
+	CompressionLevel int `json:"compression_level"`
+	// DetectSecrets scans content for credentials before writing and
+	// handles findings according to SecretsAction (warn, redact or abort).
+	DetectSecrets bool   `json:"detect_secrets"`
+	SecretsAction string `json:"secrets_action"`
+
+	// Profiles are named bundles of settings selected with -profile and
+	// decoded over the rest of the configuration file. They are kept
+	// undecoded so that every key a profile gives applies, false and 0
+	// included.
+	Profiles map[string]json.RawMessage `json:"profiles,omitempty"`
+	// Include lists configuration files loaded before this one, which then
+	// overrides them. Relative paths are resolved from the including file.
+	Include []string `json:"include,omitempty"`
+
+	// Modification-time cutoffs resolved from NewerThan/OlderThan.
+	modifiedAfter  time.Time
+	modifiedBefore time.Time
+
+	// ownArtifacts holds the absolute paths of files pecel writes itself, so a
+	// later run over the same tree never bundles them.
+	ownArtifacts map[string]bool
+
+	// remoteInput is the sftp:// URL, without credentials, that InputDir
+	// is read from; empty for a local input.
+	remoteInput string
+	// inputFS serves the input tree of an sftp:// input; nil for a local one.
+	inputFS inputFS
+
+	// contentRegex is ContentGrep compiled.
+	contentRegex *regexp.Regexp
+	// excludeGlob and includeGlob are ExcludeGlobs and IncludeGlobs compiled.
+	excludeGlob *regexp.Regexp
+	includeGlob *regexp.Regexp
+	// extensionRenames is the parsed RenameExtensions.
+	extensionRenames map[string]string
+	// langMap is the parsed LangMap.
+	langMap map[string]string
+	// timeout is the parsed Timeout.
+	timeout time.Duration
+	// allowPaths and denyPaths hold the loaded -allow-list and -deny-list.
+	allowPaths map[string]bool
+	denyPaths  map[string]bool
+	// fileOrder maps the paths in OrderFile to their position.
+	fileOrder map[string]int
+	// jsonPrettyMaxBytes and jsonPrettyMaxFiles are the parsed
+	// JSONPrettyThreshold; zero when that limit is unset.
+	jsonPrettyMaxBytes int64
+	jsonPrettyMaxFiles int
+	// splitBytes is the parsed SplitSize; zero when the output is not split
+	// by size.
+	splitBytes int64
+
+	// editorConfigs caches parsed .editorconfig files for RespectEditorConfig.
+	editorConfigs *editorConfigCache
+	// gitignores caches parsed .gitignore files for GitIgnore.
+	gitignores *gitignoreCache
+
+	// progress publishes to ProgressFile; nil when it is unset.
+	progress *progressReporter
+	// headerTemplate and footerTemplate are the parsed template files.
+	headerTemplate *template.Template
+	footerTemplate *template.Template
+	// readLimiter paces file reads under ReadRateLimit; nil when unlimited.
+	readLimiter *rateLimiter
+	// gitRepo is set when -git-author is on and the input is a git work tree.
+	gitRepo bool
+	// console serializes messages from file-processing workers; nil outside
+	// them.
+	console *console
+	// totalFiles is the number of files in the whole bundle, for the "of M"
+	// in NumberFiles labels when the output is split into parts.
+	totalFiles int
+	// jsonStream receives -output-json-streaming-to-stdout lines; nil
+	// otherwise.
+	jsonStream io.Writer
+	// separatorWidth is SeparatorWidth resolved against the output.
+	separatorWidth int
 }
 
 type FileInfo struct {
@@ -47,6 +291,30 @@ type FileInfo struct {
 	Modified     string `json:"modified" xml:"modified"`
 	Content      string `json:"content,omitempty" xml:"content,omitempty"`
 	RelativePath string `json:"relative_path" xml:"relative_path"`
+	Hash         string `json:"hash,omitempty" xml:"hash,omitempty"`
+	DiffStatus   string `json:"diff_status,omitempty" xml:"diff_status,omitempty"`
+	ContentType  string `json:"content_type,omitempty" xml:"content_type,omitempty"`
+	LinkTarget   string `json:"link_target,omitempty" xml:"link_target,omitempty"`
+	// ContentOmitted marks metadata-only entries (-content-max-depth).
+	ContentOmitted bool `json:"content_omitted,omitempty" xml:"content_omitted,omitempty"`
+	// ContentRef points to the blob holding the content when it is not
+	// inlined (-externalize-content).
+	ContentRef string `json:"content_ref,omitempty" xml:"content_ref,omitempty"`
+	// LineCount is the number of lines in the file (-line-counts).
+	LineCount int `json:"line_count,omitempty" xml:"line_count,omitempty"`
+	// Index is the file's 1-based position in the bundle (-number-files).
+	Index int `json:"index,omitempty" xml:"index,omitempty"`
+	// Encoding is "hexdump" or "base64" when Content encodes a binary file.
+	Encoding string `json:"encoding,omitempty" xml:"encoding,omitempty"`
+	// LastAuthor and LastCommitDate describe the last commit to touch the
+	// file (-git-author).
+	LastAuthor     string `json:"last_author,omitempty" xml:"last_author,omitempty"`
+	LastCommitDate string `json:"last_commit_date,omitempty" xml:"last_commit_date,omitempty"`
+
+	modTime time.Time
+	// walkIndex is the file's position in the walk, so results read in
+	// another order can be put back.
+	walkIndex int
 }
 
 type Stats struct {
@@ -54,7 +322,47 @@ type Stats struct {
 	Directories    int     `json:"directories"`
 	TotalBytes     int64   `json:"total_bytes"`
 	Duration       float64 `json:"duration_seconds"`
-	OutputSize     int64   `json:"output_size"`
+	// WalkDuration, ProcessDuration and WriteDuration split Duration into
+	// discovering files, reading and transforming them, and writing output.
+	WalkDuration    float64 `json:"walk_seconds"`
+	ProcessDuration float64 `json:"process_seconds"`
+	WriteDuration   float64 `json:"write_seconds"`
+	OutputSize      int64   `json:"output_size"`
+	// UncompressedSize is the size of the rendered output before compression;
+	// OutputSize is what ends up on disk.
+	UncompressedSize int64 `json:"uncompressed_size"`
+	// TotalLines sums the files' line counts (-line-counts).
+	TotalLines int `json:"total_lines,omitempty"`
+	// SpecialSkipped counts pipes, sockets and devices left out of the walk.
+	SpecialSkipped int `json:"special_skipped"`
+	// Vanished counts files removed between the walk and the read.
+	Vanished int `json:"vanished"`
+	// PathTooLong counts files skipped because the OS rejected their path
+	// for its length.
+	PathTooLong int `json:"path_too_long"`
+	// DroppedByName counts files left out by -dedup-by-name.
+	DroppedByName int `json:"dropped_by_name"`
+	// Errors counts files that could not be processed.
+	Errors int `json:"errors"`
+	// LargeBinarySkipped lists files left out by the large-binary guard.
+	LargeBinarySkipped []string `json:"large_binary_skipped,omitempty"`
+	// BinariesSkipped counts the other files left out for looking binary.
+	BinariesSkipped int `json:"binaries_skipped"`
+	// OutputParts lists the files written when the output is split.
+	OutputParts []string `json:"output_parts,omitempty"`
+	// ResumedParts counts the parts kept from an earlier run.
+	ResumedParts int `json:"resumed_parts,omitempty"`
+	// Diff summarizes the comparison with a previous bundle (-diff-against).
+	Diff *DiffSummary `json:"diff,omitempty"`
+	// Duplicates groups files with identical content (-report-duplicates).
+	Duplicates []DuplicateGroup `json:"duplicates,omitempty"`
+	// ContentBlobs counts the distinct blobs written or reused by
+	// -externalize-content.
+	ContentBlobs int `json:"content_blobs,omitempty"`
+	// Groups totals files and bytes per top-level directory (-group-summary).
+	Groups []DirGroup `json:"groups,omitempty"`
+	// Oversize lists the files -max-size left out (-quarantine-oversize).
+	Oversize []OversizeFile `json:"oversize,omitempty"`
 }
 
 var (
@@ -120,8 +428,28 @@ func validateExtensions(extStr string) error {
 	return nil
 }
 
+// backCommand is the answer that returns to the previous interactive question.
+const backCommand = "back"
+
+// errGoBack is returned by the prompt helpers when the user answers backCommand.
+var errGoBack = errors.New("go back")
+
+// stdinReader is shared by all prompts so buffered input is not lost between them.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// readAnswer reads one trimmed line from stdin and reports errGoBack when the
+// user asked to return to the previous question.
+func readAnswer() (string, error) {
+	input, _ := stdinReader.ReadString('\n')
+	input = strings.TrimSpace(input)
+	if strings.EqualFold(input, backCommand) {
+		return "", errGoBack
+	}
+	return input, nil
+}
+
 // Function to prompt user for input with validation
-func promptUserWithValidation(prompt string, defaultValue string, validator func(string) error) string {
+func promptUserWithValidation(prompt string, defaultValue string, validator func(string) error) (string, error) {
 	for {
 		fmt.Printf("%s %s", cyan("?"), prompt)
 
@@ -130,9 +458,10 @@ func promptUserWithValidation(prompt string, defaultValue string, validator func
 		}
 		fmt.Print(": ")
 
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(input)
+		input, err := readAnswer()
+		if err != nil {
+			return "", err
+		}
 
 		if input == "" {
 			input = defaultValue
@@ -145,17 +474,17 @@ func promptUserWithValidation(prompt string, defaultValue string, validator func
 			}
 		}
 
-		return input
+		return input, nil
 	}
 }
 
 // Function to prompt user for input
-func promptUser(prompt string, defaultValue string) string {
+func promptUser(prompt string, defaultValue string) (string, error) {
 	return promptUserWithValidation(prompt, defaultValue, nil)
 }
 
 // Function to prompt user for boolean input
-func promptBool(prompt string, defaultValue bool) bool {
+func promptBool(prompt string, defaultValue bool) (bool, error) {
 	fmt.Printf("%s %s (Y/n)", cyan("?"), prompt)
 	if defaultValue {
 		fmt.Print(" [Y]: ")
@@ -163,19 +492,21 @@ func promptBool(prompt string, defaultValue bool) bool {
 		fmt.Print(" [n]: ")
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(strings.ToLower(input))
+	input, err := readAnswer()
+	if err != nil {
+		return defaultValue, err
+	}
+	input = strings.ToLower(input)
 
 	if input == "" {
-		return defaultValue
+		return defaultValue, nil
 	}
 
-	return input == "y" || input == "yes" || input == "true" || input == "1"
+	return input == "y" || input == "yes" || input == "true" || input == "1", nil
 }
 
 // Function to prompt user for selection from options
-func promptSelect(prompt string, options []string, defaultValue string) string {
+func promptSelect(prompt string, options []string, defaultValue string) (string, error) {
 	fmt.Printf("%s %s\n", cyan("?"), prompt)
 	for i, option := range options {
 		fmt.Printf("  %d) %s", i+1, option)
@@ -186,52 +517,208 @@ func promptSelect(prompt string, options []string, defaultValue string) string {
 	}
 	fmt.Print(": ")
 
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input)
+	input, err := readAnswer()
+	if err != nil {
+		return defaultValue, err
+	}
 
 	if input == "" {
-		return defaultValue
+		return defaultValue, nil
 	}
 
 	// Try to parse as number
 	if num, err := strconv.Atoi(input); err == nil && num >= 1 && num <= len(options) {
-		return options[num-1]
+		return options[num-1], nil
 	}
 
 	// Check if input matches any option
 	for _, option := range options {
 		if strings.EqualFold(option, input) {
-			return option
+			return option, nil
 		}
 	}
 
 	// Return default if input doesn't match
-	return defaultValue
+	return defaultValue, nil
+}
+
+// interactiveStep is one question of the interactive flow. ask prompts for the
+// value (using the current value as default) and stores it in the variable of
+// the command line flag named by flag; show renders the current value for the
+// review screen.
+type interactiveStep struct {
+	label string
+	flag  string
+	ask   func() error
+	show  func() string
+}
+
+// runInteractiveSteps walks through steps in order, moving back one question
+// whenever a prompt returns errGoBack, then shows a review screen where any
+// answer can be edited again before confirming.
+func runInteractiveSteps(steps []interactiveStep) {
+	for i := 0; i < len(steps); {
+		if err := steps[i].ask(); err == errGoBack {
+			if i > 0 {
+				i--
+			} else {
+				fmt.Printf("%s Already at the first question\n", yellow("⚠"))
+			}
+			continue
+		}
+		i++
+	}
+
+	for {
+		fmt.Printf("\n%s Review your selections:\n", cyan("→"))
+		for i, step := range steps {
+			fmt.Printf("  %2d) %-24s %s\n", i+1, step.label, green(step.show()))
+		}
+		fmt.Printf("%s Enter a number to edit, or press Enter to start: ", cyan("?"))
+
+		input, err := readAnswer()
+		if err == errGoBack {
+			// There is no previous question from the review screen.
+			continue
+		}
+		if input == "" {
+			// Confirmed answers count as flags given on the command line, so
+			// a configuration file found afterwards does not override them
+			for _, step := range steps {
+				if step.flag != "" {
+					flag.Set(step.flag, flag.Lookup(step.flag).Value.String())
+				}
+			}
+			return
+		}
+		num, convErr := strconv.Atoi(input)
+		if convErr != nil || num < 1 || num > len(steps) {
+			fmt.Printf("%s Please enter a number between 1 and %d\n", red("✗"), len(steps))
+			continue
+		}
+		// Going back from an edited question just returns to the review.
+		steps[num-1].ask()
+	}
+}
+
+// Command line flags. They are package-level so the flag set can be
+// inspected as a whole, as applyFlagOverrides and its test do.
+var (
+	inputDir                 = flag.String("input", ".", "Input directory path (\"-\" reads stdin, sftp://user@host/path reads over SSH)")
+	inputShort               = flag.String("i", "", "Input directory path (shorthand)")
+	outputFile               = flag.String("output", "combined.txt", "Output file path, an s3:// or http(s):// URL to upload to, or \"-\" for stdout")
+	outputShort              = flag.String("o", "", "Output file path or upload URL (shorthand)")
+	extensions               = flag.String("ext", "", "Comma-separated list of file extensions to include")
+	excludeHidden            = flag.Bool("exclude-hidden", true, "Exclude hidden files and directories")
+	excludeShort             = flag.Bool("eh", true, "Exclude hidden files (shorthand)")
+	maxFileSize              = flag.Int64("max-size", 0, "Maximum file size in bytes (0 = unlimited)")
+	minFileSize              = flag.Int64("min-size", 0, "Minimum file size in bytes")
+	excludePattern           = flag.String("exclude", "", "Regex pattern to exclude files")
+	includePattern           = flag.String("include", "", "Regex pattern to include files")
+	excludeGlob              = flag.String("exclude-glob", "", "Comma-separated glob patterns to exclude files (e.g. *.test.go,docs/**)")
+	includeGlob              = flag.String("include-glob", "", "Comma-separated glob patterns to include files")
+	filterTest               = flag.Bool("interactive-filter-test", false, "Preview and tune -exclude/-include against the input before running")
+	scanExts                 = flag.Bool("scan-extensions", false, "Print the files and bytes per extension found under the input, then exit")
+	allowList                = flag.String("allow-list", "", "File of exact relative paths to include, one per line")
+	denyList                 = flag.String("deny-list", "", "File of exact relative paths to exclude, one per line")
+	orderFile                = flag.String("order-file", "", "File of relative paths, one per line, to put first in the output in that order")
+	outputFormatFlag         = flag.String("format", "text", "Output format: text, json, xml, yaml, markdown, html-app, csv, sqlite")
+	compress                 = flag.Bool("compress", false, "Compress output with gzip")
+	compression              = flag.String("compression", "", "Output compression: none, gzip or zstd (-compress is the same as gzip)")
+	compressionLevel         = flag.Int("compression-level", 0, "Compression level: 1-9 for gzip, 1-22 for zstd (0 = codec default)")
+	compressWorkers          = flag.Int("compress-workers", 0, "Goroutines compressing large outputs in parallel blocks (0 = same as -parallel)")
+	dryRun                   = flag.Bool("dry-run", false, "Show what would be processed without writing")
+	quiet                    = flag.Bool("quiet", false, "Suppress non-essential output")
+	verbose                  = flag.Bool("verbose", false, "Show detailed progress")
+	streamJSONStdout         = flag.Bool("output-json-streaming-to-stdout", false, "Stream one JSON object per file to stdout as files are processed")
+	parallel                 = flag.Int("parallel", 0, "Number of files to process in parallel (0 = number of CPUs, 1 = sequential)")
+	readRateLimit            = flag.Int64("read-rate-limit", 0, "Maximum bytes per second read from disk across all workers (0 = unlimited)")
+	readOrderFlag            = flag.String("read-order", readOrderDirectory, "Order files are read in: directory (grouped for cache locality), size (largest first) or path")
+	walkParallel             = flag.Int("walk-parallel", 1, "Number of directories read in parallel while discovering files")
+	versionFlag              = flag.Bool("version", false, "Show version information")
+	listFormats              = flag.Bool("list-formats", false, "List the supported output formats and exit")
+	versionShort             = flag.Bool("v", false, "Show version information (shorthand)")
+	noConfig                 = flag.Bool("no-config", false, "Don't look for a .pecel.json/.pecel.yaml project configuration file")
+	noColor                  = flag.Bool("no-color", false, "Print messages without ANSI colors")
+	profile                  = flag.String("profile", "", "Apply a named profile from the configuration file")
+	marshalWorkers           = flag.Int("marshal-workers", 0, "Number of workers marshaling JSON entries (0 = sequential)")
+	jsonPrettyThreshold      = flag.String("json-pretty-threshold", "", "Write compact JSON above this many content bytes (e.g. 1000000) or files (e.g. 200files)")
+	excludeTests             = flag.Bool("exclude-tests", false, "Skip test files by common naming conventions (_test.go, *.spec.ts, test_*.py, *Test.java, ...)")
+	dedupName                = flag.String("dedup-by-name", "", "Keep one file per base name, chosen by strategy: first, largest or newest")
+	excludeVendored          = flag.Bool("exclude-vendored", false, "Skip vendored dependency directories (vendor, node_modules, site-packages, Pods, ...)")
+	gitIgnore                = flag.Bool("gitignore", false, "Skip files matched by the .gitignore files in the input directory and below")
+	newerThan                = flag.String("newer-than", "", "Only include files modified within this age (e.g. 30d, 2w, 12h)")
+	olderThan                = flag.String("older-than", "", "Only include files modified before this age (e.g. 30d, 2w, 12h)")
+	plainSummary             = flag.Bool("plain-summary", false, "Print the summary as plain key: value lines without box drawing")
+	outputMtime              = flag.String("touch-output-mtime", "", "Set the output file's modification time: \"newest\" input, RFC 3339 time, or Unix seconds")
+	chunkTokens              = flag.Int("chunk-by-tokens", 0, "Split output into name.partN.ext files of at most N estimated tokens, named after -output (0 = single file)")
+	resumeParts              = flag.Bool("resume-parts", false, "Keep -chunk-by-tokens or -split-size parts an interrupted run already completed")
+	splitSize                = flag.String("split-size", "", "Split output into name.partN.ext files of about this size, e.g. 10MB, named after -output")
+	hashFiles                = flag.Bool("hash", false, "Record a SHA-256 hash of each file's content")
+	lineCounts               = flag.Bool("line-counts", false, "Count each file's lines and show them in its section header")
+	contentHashOnly          = flag.Bool("content-hash-only", false, "Only hash each file and write a path to hash map (sha256sum lines, or a JSON object with -format json)")
+	diffAgainst              = flag.String("diff-against", "", "Compare against a previous JSON output and annotate added/modified/unchanged/removed files")
+	pathPrefixLines          = flag.Bool("path-prefix-lines", false, "Prefix every content line with relpath:lineno: for grep-friendly output")
+	goAPIOnly                = flag.Bool("go-api-only", false, "Reduce .go files to their exported API: declarations and doc comments, no function bodies")
+	keepBOM                  = flag.Bool("keep-bom", false, "Keep UTF-8 byte order marks at the start of file content instead of stripping them")
+	detectType               = flag.Bool("detect-type", false, "Detect each file's content type from its bytes rather than its extension")
+	hexdumpBinary            = flag.Bool("hexdump-binary", false, "Render binary files as a hex dump (offset, hex and ASCII columns)")
+	hexdumpWidth             = flag.Int("hexdump-width", defaultHexdumpWidth, "Bytes per line of -hexdump-binary output")
+	excludeLargeBinary       = flag.Bool("exclude-large-binary-automatically", true, "Skip files over -large-binary-threshold that look binary")
+	largeBinaryThresholdFlag = flag.Int64("large-binary-threshold", defaultLargeBinaryThreshold, "Size in bytes above which binary-looking files are skipped")
+	binaryThresholdFlag      = flag.Float64("binary-threshold", defaultBinaryThreshold, "Fraction of non-text bytes in the sampled start of a file above which it counts as binary")
+	skipBinaryFlag           = flag.Bool("skip-binary", true, "Skip files whose first 8 KB look binary (NUL bytes or non-text content)")
+	base64Binary             = flag.Bool("base64-binary", false, "Include binary files base64-encoded instead of skipping them")
+	excludeSymlinks          = flag.Bool("exclude-symlinks", false, "Skip symbolic links instead of reading what they point to")
+	followSymlinks           = flag.Bool("follow-symlinks", false, "Walk into symlinked directories, skipping cycles")
+	materializeSymlinks      = flag.Bool("materialize-symlinks", false, "Include each symlink as a copy of its target under the link's path (implies -follow-symlinks)")
+	maxSymlinkDepthFlag      = flag.Int("max-symlink-depth", defaultMaxSymlinkDepth, "Maximum symlink hops followed along one path before giving up")
+	symlinkMetadata          = flag.Bool("include-symlink-targets-as-metadata", false, "Record skipped symlinks and their targets as metadata-only entries (implies -exclude-symlinks)")
+	contentGrep              = flag.String("content-grep", "", "Regex pattern file contents must match to be included")
+	snippetLines             = flag.Int("snippet-lines", 0, "With -content-grep, include only N lines of context around each match")
+	headerTemplateFile       = flag.String("header-template-file", "", "Go template rendering the document header (text, markdown, html-app)")
+	footerTemplateFile       = flag.String("footer-template-file", "", "Go template rendering the document footer (text, markdown, html-app)")
+	noHeader                 = flag.Bool("no-header", false, "Leave out the document header (text, markdown, html-app)")
+	noFooter                 = flag.Bool("no-footer", false, "Leave out the summary footer (text, markdown, html-app)")
+	renameExtensions         = flag.String("rename-extension", "", "With -output-dir-mirror, rewrite file extensions (from=to, comma-separated)")
+	externalizeContentDir    = flag.String("externalize-content", "", "Write each distinct content to dir/<sha256> and reference it from JSON or XML output instead of inlining it")
+	outputDirMirror          = flag.String("output-dir-mirror", "", "Write each transformed file to this directory, mirroring the input tree, instead of combining")
+	maxOutputLines           = flag.Int("max-output-lines", 0, "Cap text/markdown output at N lines, stopping at a file boundary (0 = unlimited)")
+	respectEditorConfig      = flag.Bool("respect-editorconfig", false, "Normalize content with the project's .editorconfig rules")
+	watchMode                = flag.Bool("watch", false, "Rebuild the output whenever files in the input directory change")
+	contentMaxDepth          = flag.Int("content-max-depth", 0, "Read content only for files within N directory levels of the input; list deeper files as metadata (0 = unlimited)")
+	metricsFile              = flag.String("metrics-file", "", "Write the run's stats in Prometheus textfile format to this file")
+	progressFile             = flag.String("progress-file", "", "Periodically write JSON progress ({done, total, bytes, eta}) to this file or named pipe")
+	timeout                  = flag.String("timeout", "", "Stop reading after this long (e.g. 30s) and write the files processed so far")
+	compactEmpty             = flag.Bool("compact-empty-sections", false, "Collapse files with no content left to a single line in text and markdown output")
+	numberFilesFlag          = flag.Bool("number-files", false, "Label each file section \"File N of M\" and record its index in JSON/XML")
+	vanishedFiles            = flag.String("vanished-files", vanishedInfo, "How to report files removed after the scan: info, ignore or error")
+	gitAuthor                = flag.Bool("git-author", false, "Show each file's last commit author and date (git repositories only)")
+	reportDuplicates         = flag.Bool("report-duplicates", false, "Report groups of files with identical content and the space they waste")
+	groupSummary             = flag.Bool("group-summary", false, "Break the summary down by top-level directory")
+	quarantineOversize       = flag.Bool("quarantine-oversize", false, "List the files -max-size leaves out, with their sizes, after the summary")
+	manifest                 = flag.String("manifest", "", "Also write a sha256sum-style manifest of file hashes to this path")
+	verifyManifestPath       = flag.String("verify-against-manifest", "", "Check the tree against a manifest (or JSON bundle) and exit non-zero on drift")
+	separatorWidth           = flag.Int("separator-width", 0, "Width of the separator lines in text output (0 = terminal width on a TTY, else 80)")
+	markdownBlockLines       = flag.Int("markdown-block-lines", 0, "Split markdown code blocks longer than N lines into continued blocks (0 = never)")
+	markdownTOCFlag          = flag.Bool("markdown-toc", false, "Add a table of contents linking to each file to markdown output")
+	csvIncludeContent        = flag.Bool("csv-include-content", false, "Add a content column to csv output")
+	langMap                  = flag.String("lang-map", "", "Override markdown fence languages by extension (.ext=lang, comma-separated)")
+	maxBlankLines            = flag.Int("max-blank-lines", 0, "Cap runs of consecutive blank lines in content at N (0 = keep all)")
+	detectSecrets            = flag.Bool("detect-secrets", false, "Scan content for secrets (AWS keys, private keys, tokens) before writing")
+	secretsAction            = flag.String("secrets-action", secretsWarn, "What -detect-secrets does with findings: warn, redact or abort")
+	rootLabel                = flag.String("root-label", "", "Prefix every relative path with this label (e.g. the project name)")
+	relativeTime             = flag.Bool("relative-time", false, "Show modification times as relative ages in text and markdown headers")
+)
+
+// configFiles collects the repeatable -config flag.
+var configFiles configList
+
+func init() {
+	flag.Var(&configFiles, "config", "Load configuration from a JSON or YAML file; repeat to layer files, each overriding the settings it gives, false and 0 included")
 }
 
 func main() {
-	// Define command line flags with short versions
-	inputDir := flag.String("input", ".", "Input directory path")
-	inputShort := flag.String("i", "", "Input directory path (shorthand)")
-	outputFile := flag.String("output", "combined.txt", "Output file path")
-	outputShort := flag.String("o", "", "Output file path (shorthand)")
-	extensions := flag.String("ext", "", "Comma-separated list of file extensions to include")
-	excludeHidden := flag.Bool("exclude-hidden", true, "Exclude hidden files and directories")
-	excludeShort := flag.Bool("eh", true, "Exclude hidden files (shorthand)")
-	maxFileSize := flag.Int64("max-size", 0, "Maximum file size in bytes (0 = unlimited)")
-	minFileSize := flag.Int64("min-size", 0, "Minimum file size in bytes")
-	excludePattern := flag.String("exclude", "", "Regex pattern to exclude files")
-	includePattern := flag.String("include", "", "Regex pattern to include files")
-	outputFormat := flag.String("format", "text", "Output format: text, json, xml, markdown")
-	compress := flag.Bool("compress", false, "Compress output with gzip")
-	dryRun := flag.Bool("dry-run", false, "Show what would be processed without writing")
-	quiet := flag.Bool("quiet", false, "Suppress non-essential output")
-	verbose := flag.Bool("verbose", false, "Show detailed progress")
-	parallel := flag.Int("parallel", 1, "Number of files to process in parallel")
-	versionFlag := flag.Bool("version", false, "Show version information")
-	versionShort := flag.Bool("v", false, "Show version information (shorthand)")
-	configFile := flag.String("config", "", "Load configuration from JSON file")
 
 	// Parse flags early to check if any were provided
 	flag.Parse()
@@ -255,146 +742,406 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *listFormats {
+		printFormats(os.Stdout)
+		os.Exit(0)
+	}
+
 	// Check if no flags were provided and enter interactive mode
 	if !hasAnyFlagSet() && len(os.Args) == 1 {
-		fmt.Printf("%s Welcome to Pecel v%s - Interactive Mode\n\n", cyan("→"), version)
-
-		// Prompt for input directory with validation
-		*inputDir = promptUserWithValidation("Enter input directory path", ".", validateDirectory)
+		fmt.Printf("%s Welcome to Pecel v%s - Interactive Mode\n", cyan("→"), version)
+		fmt.Printf("Type '%s' at any prompt to return to the previous question.\n\n", backCommand)
 
-		// Prompt for output file with validation
-		*outputFile = promptUserWithValidation("Enter output file path", "combined.txt", validateFilePath)
-
-		// Prompt for file extensions with validation
-		extInput := promptUserWithValidation("Enter file extensions to include (comma-separated, e.g., .go,.js,.py)", "", validateExtensions)
-		if extInput != "" {
-			*extensions = extInput
+		available := availableFormats()
+		formats := make([]string, len(available))
+		for i, f := range available {
+			formats[i] = f.Name
 		}
-
-		// Prompt for output format
-		formats := []string{"text", "json", "xml", "markdown"}
-		*outputFormat = promptSelect("Select output format", formats, "text")
-
-		// Prompt for excluding hidden files
-		*excludeHidden = promptBool("Exclude hidden files and directories", true)
-
-		// Prompt for compression
-		*compress = promptBool("Compress output with gzip", false)
-
-		// Prompt for max file size
-		maxSizeStr := promptUser("Maximum file size in bytes (0 for unlimited)", "0")
-		if val, err := strconv.ParseInt(maxSizeStr, 10, 64); err == nil && val >= 0 {
-			*maxFileSize = val
-		} else {
-			*maxFileSize = 0
+		workerCount := func(value string) error {
+			if val, err := strconv.Atoi(value); err != nil || val < 0 {
+				return errors.New("parallel value must be 0 (all CPUs) or a positive integer")
+			}
+			return nil
 		}
-
-		// Prompt for exclude pattern
-		excludePat := promptUser("Regex pattern to exclude files (optional)", "")
-		*excludePattern = excludePat
-
-		// Prompt for include pattern
-		includePat := promptUser("Regex pattern to include files (optional)", "")
-		*includePattern = includePat
-
-		// Prompt for parallel processing with validation
-		for {
-			parallelStr := promptUser("Number of files to process in parallel", "1")
-			if val, err := strconv.Atoi(parallelStr); err == nil && val > 0 {
-				*parallel = val
-				break
-			} else if err != nil || val <= 0 {
-				fmt.Printf("%s Parallel value must be a positive integer\n", red("✗"))
-				continue
+		nonNegativeSize := func(value string) error {
+			if val, err := strconv.ParseInt(value, 10, 64); err != nil || val < 0 {
+				return errors.New("size must be a non-negative number of bytes")
+			}
+			return nil
+		}
+		orNone := func(value string) string {
+			if value == "" {
+				return "(none)"
+			}
+			return value
+		}
+		askString := func(target *string, prompt string, validator func(string) error) func() error {
+			return func() error {
+				value, err := promptUserWithValidation(prompt, *target, validator)
+				if err == nil {
+					*target = value
+				}
+				return err
+			}
+		}
+		askBool := func(target *bool, prompt string) func() error {
+			return func() error {
+				value, err := promptBool(prompt, *target)
+				if err == nil {
+					*target = value
+				}
+				return err
 			}
 		}
 
-		// Prompt for verbose mode
-		*verbose = promptBool("Enable verbose output", false)
-
-		// Prompt for dry run
-		*dryRun = promptBool("Perform dry run (show what would be processed without writing)", false)
+		runInteractiveSteps([]interactiveStep{
+			{
+				label: "Input directory",
+				flag:  "input",
+				ask:   askString(inputDir, "Enter input directory path", validateDirectory),
+				show:  func() string { return *inputDir },
+			},
+			{
+				label: "Output file",
+				flag:  "output",
+				ask:   askString(outputFile, "Enter output file path", validateFilePath),
+				show:  func() string { return *outputFile },
+			},
+			{
+				label: "Extensions",
+				flag:  "ext",
+				ask:   askString(extensions, "Enter file extensions to include (comma-separated, e.g., .go,.js,.py)", validateExtensions),
+				show:  func() string { return orNone(*extensions) },
+			},
+			{
+				label: "Output format",
+				flag:  "format",
+				ask: func() error {
+					value, err := promptSelect("Select output format", formats, *outputFormatFlag)
+					if err == nil {
+						*outputFormatFlag = value
+					}
+					return err
+				},
+				show: func() string { return *outputFormatFlag },
+			},
+			{
+				label: "Exclude hidden",
+				flag:  "exclude-hidden",
+				ask:   askBool(excludeHidden, "Exclude hidden files and directories"),
+				show:  func() string { return strconv.FormatBool(*excludeHidden) },
+			},
+			{
+				label: "Compress",
+				flag:  "compress",
+				ask:   askBool(compress, "Compress output with gzip"),
+				show:  func() string { return strconv.FormatBool(*compress) },
+			},
+			{
+				label: "Max file size",
+				flag:  "max-size",
+				ask: func() error {
+					value, err := promptUserWithValidation("Maximum file size in bytes (0 for unlimited)",
+						strconv.FormatInt(*maxFileSize, 10), nonNegativeSize)
+					if err == nil {
+						*maxFileSize, _ = strconv.ParseInt(value, 10, 64)
+					}
+					return err
+				},
+				show: func() string { return strconv.FormatInt(*maxFileSize, 10) },
+			},
+			{
+				label: "Exclude pattern",
+				flag:  "exclude",
+				ask:   askString(excludePattern, "Regex pattern to exclude files (optional)", nil),
+				show:  func() string { return orNone(*excludePattern) },
+			},
+			{
+				label: "Include pattern",
+				flag:  "include",
+				ask:   askString(includePattern, "Regex pattern to include files (optional)", nil),
+				show:  func() string { return orNone(*includePattern) },
+			},
+			{
+				label: "Filter test",
+				ask: func() error {
+					test, err := promptBool("Preview which files the patterns keep, and tune them", false)
+					if err != nil || !test {
+						return err
+					}
+					cfg := Config{
+						InputDir:       *inputDir,
+						ExcludeHidden:  *excludeHidden,
+						MaxFileSize:    *maxFileSize,
+						ExcludePattern: *excludePattern,
+						IncludePattern: *includePattern,
+					}
+					if *extensions != "" {
+						cfg.Extensions = strings.Split(*extensions, ",")
+					}
+					runFilterTest(&cfg)
+					*excludePattern, *includePattern = cfg.ExcludePattern, cfg.IncludePattern
+					return nil
+				},
+				show: func() string { return "select to preview" },
+			},
+			{
+				label: "Parallel workers",
+				flag:  "parallel",
+				ask: func() error {
+					value, err := promptUserWithValidation("Number of files to process in parallel",
+						strconv.Itoa(*parallel), workerCount)
+					if err == nil {
+						*parallel, _ = strconv.Atoi(value)
+					}
+					return err
+				},
+				show: func() string { return strconv.Itoa(*parallel) },
+			},
+			{
+				label: "Verbose",
+				flag:  "verbose",
+				ask:   askBool(verbose, "Enable verbose output"),
+				show:  func() string { return strconv.FormatBool(*verbose) },
+			},
+			{
+				label: "Dry run",
+				flag:  "dry-run",
+				ask:   askBool(dryRun, "Perform dry run (show what would be processed without writing)"),
+				show:  func() string { return strconv.FormatBool(*dryRun) },
+			},
+		})
 
 		fmt.Println()
 		fmt.Printf("%s Starting processing with your selections...\n\n", green("✓"))
 	}
 
-	// Load config file if specified
+	// Streaming owns stdout; every message goes to stderr instead
+	var jsonStream io.Writer
+	if *streamJSONStdout {
+		jsonStream = os.Stdout
+		os.Stdout = os.Stderr
+	}
+	if *outputFile == stdoutOutput {
+		os.Stdout = os.Stderr
+	}
+	setColorOutput(*noColor)
+
+	// Load config file if specified, otherwise the nearest project one
+	if len(configFiles) == 0 && !*noConfig {
+		if found := discoverConfig(); found != "" {
+			configFiles = configList{found}
+			if !*quiet {
+				fmt.Printf("%s Using configuration file %s\n", cyan("→"), found)
+			}
+		}
+	}
 	var config Config
-	if *configFile != "" {
-		cfg, err := loadConfig(*configFile)
+	if len(configFiles) > 0 {
+		cfg, err := loadConfigs(configFiles)
 		if err != nil {
 			fmt.Printf("%s Error loading config: %v\n", red("✗"), err)
 			os.Exit(1)
 		}
+		if *profile != "" {
+			cfg, err = applyProfile(cfg, *profile)
+			if err != nil {
+				fmt.Printf("%s %v\n", red("✗"), err)
+				os.Exit(1)
+			}
+		}
 		config = cfg
-		// Override with command line flags if provided
-		if *inputDir != "." {
-			config.InputDir = *inputDir
+		applyFlagOverrides(&config)
+	} else {
+		if *profile != "" {
+			fmt.Printf("%s -profile requires a configuration file (-config)\n", red("✗"))
+			os.Exit(1)
 		}
-		if *outputFile != "combined.txt" {
-			config.OutputFile = *outputFile
+		config = Config{
+			InputDir:             *inputDir,
+			OutputFile:           *outputFile,
+			ExcludeHidden:        *excludeHidden,
+			MaxFileSize:          *maxFileSize,
+			MinFileSize:          *minFileSize,
+			ExcludePattern:       *excludePattern,
+			IncludePattern:       *includePattern,
+			AllowList:            *allowList,
+			DenyList:             *denyList,
+			OrderFile:            *orderFile,
+			OutputFormat:         *outputFormatFlag,
+			Compress:             *compress,
+			CompressWorkers:      *compressWorkers,
+			Compression:          *compression,
+			CompressionLevel:     *compressionLevel,
+			Parallel:             *parallel,
+			WalkParallel:         *walkParallel,
+			ReadOrder:            *readOrderFlag,
+			ReadRateLimit:        *readRateLimit,
+			Quiet:                *quiet,
+			Verbose:              *verbose,
+			DryRun:               *dryRun,
+			MarshalWorkers:       *marshalWorkers,
+			JSONPrettyThreshold:  *jsonPrettyThreshold,
+			RelativeTime:         *relativeTime,
+			PlainSummary:         *plainSummary,
+			OutputMtime:          *outputMtime,
+			ChunkTokens:          *chunkTokens,
+			ResumeParts:          *resumeParts,
+			SplitSize:            *splitSize,
+			Hash:                 *hashFiles,
+			LineCounts:           *lineCounts,
+			ContentHashOnly:      *contentHashOnly,
+			PathPrefixLines:      *pathPrefixLines,
+			GoAPIOnly:            *goAPIOnly,
+			DetectType:           *detectType,
+			KeepBOM:              *keepBOM,
+			RootLabel:            *rootLabel,
+			KeepLargeBinaries:    !*excludeLargeBinary,
+			HexdumpBinary:        *hexdumpBinary,
+			HexdumpWidth:         *hexdumpWidth,
+			LargeBinaryThreshold: *largeBinaryThresholdFlag,
+			BinaryThreshold:      *binaryThresholdFlag,
+			KeepBinaries:         !*skipBinaryFlag,
+			Base64Binary:         *base64Binary,
+			ExcludeSymlinks:      *excludeSymlinks,
+			SymlinkMetadata:      *symlinkMetadata,
+			FollowSymlinks:       *followSymlinks,
+			MaterializeSymlinks:  *materializeSymlinks,
+			MaxSymlinkDepth:      *maxSymlinkDepthFlag,
+			ContentGrep:          *contentGrep,
+			SnippetLines:         *snippetLines,
+			OutputDirMirror:      *outputDirMirror,
+			ExternalizeContent:   *externalizeContentDir,
+			RenameExtensions:     *renameExtensions,
+			HeaderTemplateFile:   *headerTemplateFile,
+			NoHeader:             *noHeader,
+			NoFooter:             *noFooter,
+			FooterTemplateFile:   *footerTemplateFile,
+			MaxOutputLines:       *maxOutputLines,
+			RespectEditorConfig:  *respectEditorConfig,
+			MarkdownBlockLines:   *markdownBlockLines,
+			LangMap:              *langMap,
+			MarkdownTOC:          *markdownTOCFlag,
+			CSVIncludeContent:    *csvIncludeContent,
+			MaxBlankLines:        *maxBlankLines,
+			SeparatorWidth:       *separatorWidth,
+			Watch:                *watchMode,
+			ContentMaxDepth:      *contentMaxDepth,
+			ProgressFile:         *progressFile,
+			Timeout:              *timeout,
+			MetricsFile:          *metricsFile,
+			ReportDuplicates:     *reportDuplicates,
+			GroupSummary:         *groupSummary,
+			QuarantineOversize:   *quarantineOversize,
+			GitAuthor:            *gitAuthor,
+			VanishedFiles:        *vanishedFiles,
+			CompactEmptySections: *compactEmpty,
+			NumberFiles:          *numberFilesFlag,
+			DetectSecrets:        *detectSecrets,
+			SecretsAction:        *secretsAction,
+			Manifest:             *manifest,
+			VerifyManifest:       *verifyManifestPath,
+			DiffAgainst:          *diffAgainst,
+			NewerThan:            *newerThan,
+			ExcludeVendored:      *excludeVendored,
+			GitIgnore:            *gitIgnore,
+			ExcludeTests:         *excludeTests,
+			DedupByName:          *dedupName,
+			OlderThan:            *olderThan,
 		}
 		if *extensions != "" {
 			config.Extensions = strings.Split(*extensions, ",")
 		}
-		// Check if the exclude-hidden flag was explicitly set
-		if isFlagSet("exclude-hidden") {
-			config.ExcludeHidden = *excludeHidden
-		}
-		if *excludePattern != "" {
-			config.ExcludePattern = *excludePattern
+		if *excludeGlob != "" {
+			config.ExcludeGlobs = strings.Split(*excludeGlob, ",")
 		}
-		if *includePattern != "" {
-			config.IncludePattern = *includePattern
+		if *includeGlob != "" {
+			config.IncludeGlobs = strings.Split(*includeGlob, ",")
 		}
-		if *outputFormat != "text" {
-			config.OutputFormat = *outputFormat
+	}
+
+	// Validate input directory exists; "-" reads a single file from stdin
+	// and an sftp:// input is walked and read over the connection
+	if u, ok := remoteInputURL(config.InputDir); ok {
+		if config.Watch {
+			fmt.Printf("%s -watch needs a local input directory\n", red("✗"))
+			os.Exit(1)
 		}
-		if *compress {
-			config.Compress = *compress
+		if !config.Quiet {
+			fmt.Printf("%s Connecting to %s\n", cyan("→"), u.Redacted())
 		}
-		if *parallel != 1 {
-			config.Parallel = *parallel
+		fsys, dir, err := openRemoteInput(u)
+		if err != nil {
+			fmt.Printf("%s %v\n", red("✗"), err)
+			os.Exit(1)
 		}
-		if *quiet {
-			config.Quiet = *quiet
+		defer fsys.Close()
+		config.InputDir = dir
+		config.inputFS = fsys
+		config.remoteInput = u.Redacted()
+	} else if config.InputDir != stdinInput {
+		if err := validateDirectory(config.InputDir); err != nil {
+			fmt.Printf("%s %v\n", red("✗"), err)
+			os.Exit(1)
 		}
-		if *verbose {
-			config.Verbose = *verbose
+	}
+
+	if config.SplitSize != "" {
+		size, err := parseByteSize(config.SplitSize)
+		if err != nil {
+			fmt.Printf("%s Invalid -split-size: %v\n", red("✗"), err)
+			os.Exit(1)
 		}
-		if *dryRun {
-			config.DryRun = *dryRun
+		if config.ChunkTokens > 0 {
+			fmt.Printf("%s -split-size and -chunk-by-tokens cannot be combined\n", red("✗"))
+			os.Exit(1)
 		}
-	} else {
-		config = Config{
-			InputDir:       *inputDir,
-			OutputFile:     *outputFile,
-			ExcludeHidden:  *excludeHidden,
-			MaxFileSize:    *maxFileSize,
-			MinFileSize:    *minFileSize,
-			ExcludePattern: *excludePattern,
-			IncludePattern: *includePattern,
-			OutputFormat:   *outputFormat,
-			Compress:       *compress,
-			Parallel:       *parallel,
-			Quiet:          *quiet,
-			Verbose:        *verbose,
-			DryRun:         *dryRun,
+		config.splitBytes = size
+	}
+
+	switch config.Compression {
+	case "":
+		config.Compression = compressionNone
+		if config.Compress {
+			config.Compression = compressionGzip
 		}
-		if *extensions != "" {
-			config.Extensions = strings.Split(*extensions, ",")
+	case compressionNone:
+		if config.Compress {
+			fmt.Printf("%s -compress and -compression none cannot be combined\n", red("✗"))
+			os.Exit(1)
 		}
+	case compressionGzip, compressionZstd:
+		config.Compress = true
+	default:
+		fmt.Printf("%s Invalid -compression %q: use none, gzip or zstd\n", red("✗"), config.Compression)
+		os.Exit(1)
 	}
-
-	// Validate input directory exists
-	if err := validateDirectory(config.InputDir); err != nil {
-		fmt.Printf("%s %v\n", red("✗"), err)
+	if err := validCompressionLevel(config.Compression, config.CompressionLevel); err != nil {
+		fmt.Printf("%s Invalid -compression-level: %v\n", red("✗"), err)
 		os.Exit(1)
 	}
+	// The codec's extension is added to a local output path that lacks it
+	if ext := compressionExtensions[config.Compression]; ext != "" && config.OutputFile != stdoutOutput &&
+		!isRemoteOutput(config.OutputFile) && !strings.HasSuffix(config.OutputFile, ext) {
+		config.OutputFile += ext
+	}
 
-	// Validate output file path
-	if err := validateFilePath(config.OutputFile); err != nil {
+	// Validate output file path; URLs are checked when the upload starts
+	if config.OutputFile == stdoutOutput {
+		// Also when "-" came from a configuration file
+		os.Stdout = os.Stderr
+		setColorOutput(*noColor)
+		if *streamJSONStdout || config.OutputFormat == "sqlite" || config.OutputMtime != "" ||
+			splitsOutput(config) || config.OutputDirMirror != "" {
+			fmt.Printf("%s -output - cannot be combined with -output-json-streaming-to-stdout, sqlite output, -touch-output-mtime, -chunk-by-tokens, -split-size or -output-dir-mirror\n", red("✗"))
+			os.Exit(1)
+		}
+	} else if isRemoteOutput(config.OutputFile) {
+		if config.OutputFormat == "sqlite" || config.OutputMtime != "" {
+			fmt.Printf("%s sqlite output and -touch-output-mtime need a local -output file\n", red("✗"))
+			os.Exit(1)
+		}
+	} else if err := validateFilePath(config.OutputFile); err != nil {
 		fmt.Printf("%s %v\n", red("✗"), err)
 		os.Exit(1)
 	}
@@ -407,492 +1154,2702 @@ func main() {
 
 	startTime := time.Now()
 
-	// Validate patterns
-	var excludeRegex, includeRegex *regexp.Regexp
-	if *excludePattern != "" {
-		re, err := regexp.Compile(*excludePattern)
+	// Resolve age filters against the start of the run
+	if config.NewerThan != "" {
+		age, err := parseAge(config.NewerThan)
 		if err != nil {
-			fmt.Printf("%s Invalid exclude pattern: %v\n", red("✗"), err)
+			fmt.Printf("%s Invalid -newer-than value: %v\n", red("✗"), err)
 			os.Exit(1)
 		}
-		excludeRegex = re
+		config.modifiedAfter = startTime.Add(-age)
 	}
-	if *includePattern != "" {
-		re, err := regexp.Compile(*includePattern)
+	if config.OlderThan != "" {
+		age, err := parseAge(config.OlderThan)
 		if err != nil {
-			fmt.Printf("%s Invalid include pattern: %v\n", red("✗"), err)
+			fmt.Printf("%s Invalid -older-than value: %v\n", red("✗"), err)
 			os.Exit(1)
 		}
-		includeRegex = re
+		config.modifiedBefore = startTime.Add(-age)
 	}
 
-	if !*quiet {
-		fmt.Printf("%s Starting Pecel v%s\n", cyan("→"), version)
-		fmt.Printf("%s Input directory: %s\n", cyan("→"), config.InputDir)
-		fmt.Printf("%s Output file: %s\n", cyan("→"), config.OutputFile)
-		if *dryRun {
-			fmt.Printf("%s DRY RUN MODE - No files will be written\n", yellow("⚠"))
-		}
+	switch config.SecretsAction {
+	case "":
+		config.SecretsAction = secretsWarn
+	case secretsWarn, secretsRedact, secretsAbort:
+	default:
+		fmt.Printf("%s Invalid -secrets-action %q: use warn, redact or abort\n", red("✗"), config.SecretsAction)
+		os.Exit(1)
 	}
 
-	// Collect file information
-	var fileInfos []FileInfo
-	var filePaths []string
-	var stats Stats
+	switch config.VanishedFiles {
+	case "":
+		config.VanishedFiles = vanishedInfo
+	case vanishedInfo, vanishedIgnore, vanishedError:
+	default:
+		fmt.Printf("%s Invalid -vanished-files %q: use info, ignore or error\n", red("✗"), config.VanishedFiles)
+		os.Exit(1)
+	}
 
-	// Walk directory to collect files
-	err := filepath.Walk(config.InputDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			if !*quiet {
-				fmt.Printf("%s Error accessing %s: %v\n", red("✗"), path, err)
-			}
-			return nil
-		}
+	if config.OutputFormat == "sqlite" && (config.Compress || splitsOutput(config)) {
+		fmt.Printf("%s -compress, -chunk-by-tokens and -split-size do not apply to sqlite output\n", red("✗"))
+		os.Exit(1)
+	}
 
-		if info.IsDir() {
-			stats.Directories++
-			if config.ExcludeHidden && isHidden(info.Name()) {
-				return filepath.SkipDir
-			}
-			return nil
-		}
+	if config.ResumeParts && (!splitsOutput(config) || isRemoteOutput(config.OutputFile)) {
+		fmt.Printf("%s -resume-parts needs -chunk-by-tokens or -split-size and a local output file\n", red("✗"))
+		os.Exit(1)
+	}
 
-		// Apply filters
-		if !shouldProcessFile(path, info, config, excludeRegex, includeRegex) {
-			return nil
-		}
+	if config.Base64Binary && config.HexdumpBinary {
+		fmt.Printf("%s -base64-binary and -hexdump-binary cannot be combined\n", red("✗"))
+		os.Exit(1)
+	}
 
-		filePaths = append(filePaths, path)
-		return nil
-	})
+	if config.BinaryThreshold < 0 || config.BinaryThreshold > 1 {
+		fmt.Printf("%s -binary-threshold must be between 0 and 1\n", red("✗"))
+		os.Exit(1)
+	}
 
-	if err != nil {
-		fmt.Printf("%s Error walking directory: %v\n", red("✗"), err)
+	if config.MaxBlankLines < 0 {
+		fmt.Printf("%s -max-blank-lines must not be negative\n", red("✗"))
 		os.Exit(1)
 	}
 
-	if !*quiet {
-		fmt.Printf("%s Found %d files to process\n", cyan("→"), len(filePaths))
+	if config.HexdumpWidth < 0 {
+		fmt.Printf("%s -hexdump-width must not be negative\n", red("✗"))
+		os.Exit(1)
 	}
 
-	// Process files
-	if *parallel > 1 {
-		fileInfos = processFilesParallel(filePaths, config.InputDir, *parallel, *verbose, *quiet, &stats)
-	} else {
-		fileInfos = processFilesSequential(filePaths, config.InputDir, *verbose, *quiet, &stats)
+	if config.SeparatorWidth < 0 {
+		fmt.Printf("%s -separator-width must not be negative\n", red("✗"))
+		os.Exit(1)
 	}
 
-	stats.Duration = time.Since(startTime).Seconds()
+	if config.MaxOutputLines > 0 && config.OutputFormat != "text" && config.OutputFormat != "markdown" {
+		fmt.Printf("%s -max-output-lines only applies to text and markdown output\n", red("✗"))
+		os.Exit(1)
+	}
 
-	// Generate output
-	if !*dryRun {
-		outputSize, err := writeOutput(fileInfos, config.OutputFile, *outputFormat, *compress, stats)
-		if err != nil {
-			fmt.Printf("%s Error writing output: %v\n", red("✗"), err)
+	if config.ExternalizeContent != "" {
+		if (config.OutputFormat != "json" && config.OutputFormat != "xml") || config.OutputDirMirror != "" || jsonStream != nil {
+			fmt.Printf("%s -externalize-content needs -format json or xml output written to a file\n", red("✗"))
+			os.Exit(1)
+		}
+		if config.ContentHashOnly {
+			fmt.Printf("%s -externalize-content has no content to store with -content-hash-only\n", red("✗"))
 			os.Exit(1)
 		}
-		stats.OutputSize = outputSize
 	}
 
-	// Print summary
-	printSummary(stats, *outputFormat, *compress, *dryRun)
-
-	if *dryRun {
-		fmt.Printf("\n%s Dry run completed. %d files would be processed.\n",
-			green("✓"), stats.FilesProcessed)
-	} else {
-		fmt.Printf("\n%s Processing completed successfully!\n", green("✓"))
+	// Mirroring into the input directory would overwrite the sources
+	if config.OutputDirMirror != "" && config.InputDir != stdinInput {
+		mirrorAbs, err1 := filepath.Abs(config.OutputDirMirror)
+		inputAbs, err2 := filepath.Abs(config.InputDir)
+		if err1 == nil && err2 == nil && mirrorAbs == inputAbs {
+			fmt.Printf("%s -output-dir-mirror must differ from the input directory\n", red("✗"))
+			os.Exit(1)
+		}
 	}
-}
-
-func shouldProcessFile(path string, info os.FileInfo, config Config,
-	excludeRegex, includeRegex *regexp.Regexp) bool {
 
-	// Skip hidden files
-	if config.ExcludeHidden && isHidden(info.Name()) {
-		return false
+	if jsonStream != nil {
+		if config.DiffAgainst != "" || config.ReportDuplicates || config.Manifest != "" || config.OrderFile != "" ||
+			splitsOutput(config) || config.OutputDirMirror != "" || config.Compress || config.Watch {
+			fmt.Printf("%s -output-json-streaming-to-stdout cannot be combined with -diff-against, -report-duplicates, -manifest, -order-file, -chunk-by-tokens, -split-size, -output-dir-mirror, -compress or -watch\n", red("✗"))
+			os.Exit(1)
+		}
+		config.jsonStream = jsonStream
+		config.OutputFormat = "jsonl"
 	}
 
-	// Check file size limits
-	if config.MaxFileSize > 0 && info.Size() > config.MaxFileSize {
-		return false
+	if (config.NoHeader && config.HeaderTemplateFile != "") || (config.NoFooter && config.FooterTemplateFile != "") {
+		fmt.Printf("%s -no-header and -no-footer cannot be combined with a template for the same part\n", red("✗"))
+		os.Exit(1)
 	}
-	if config.MinFileSize > 0 && info.Size() < config.MinFileSize {
-		return false
+	if config.HeaderTemplateFile != "" {
+		tmpl, err := loadDocumentTemplate(config.HeaderTemplateFile)
+		if err != nil {
+			fmt.Printf("%s Invalid -header-template-file: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		config.headerTemplate = tmpl
+	}
+	if config.FooterTemplateFile != "" {
+		tmpl, err := loadDocumentTemplate(config.FooterTemplateFile)
+		if err != nil {
+			fmt.Printf("%s Invalid -footer-template-file: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		config.footerTemplate = tmpl
 	}
 
-	// Check extensions
-	if len(config.Extensions) > 0 {
-		ext := filepath.Ext(path)
-		found := false
-		for _, allowedExt := range config.Extensions {
-			if strings.EqualFold(ext, allowedExt) {
-				found = true
-				break
-			}
+	if config.RenameExtensions != "" {
+		if config.OutputDirMirror == "" {
+			fmt.Printf("%s -rename-extension only applies with -output-dir-mirror\n", red("✗"))
+			os.Exit(1)
 		}
-		if !found {
-			return false
+		renames, err := parseExtensionRenames(config.RenameExtensions)
+		if err != nil {
+			fmt.Printf("%s Invalid -rename-extension: %v\n", red("✗"), err)
+			os.Exit(1)
 		}
+		config.extensionRenames = renames
 	}
 
-	// Check regex patterns
-	relPath, _ := filepath.Rel(config.InputDir, path)
-	if excludeRegex != nil && excludeRegex.MatchString(relPath) {
-		return false
+	if config.CSVIncludeContent && strings.ToLower(config.OutputFormat) != "csv" {
+		fmt.Printf("%s -csv-include-content only applies with -format csv\n", red("✗"))
+		os.Exit(1)
 	}
-	if includeRegex != nil && !includeRegex.MatchString(relPath) {
-		return false
+
+	if config.MarkdownTOC && config.MaxOutputLines > 0 {
+		fmt.Printf("%s -markdown-toc cannot be combined with -max-output-lines, which could leave links to omitted files\n", red("✗"))
+		os.Exit(1)
 	}
 
-	return true
-}
+	if config.LangMap != "" {
+		langs, err := parseLangMap(config.LangMap)
+		if err != nil {
+			fmt.Printf("%s Invalid -lang-map: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		config.langMap = langs
+	}
 
-func processFilesSequential(paths []string, baseDir string, verbose, quiet bool, stats *Stats) []FileInfo {
-	var fileInfos []FileInfo
+	resolveOwnArtifacts(&config)
 
-	for i, path := range paths {
-		if verbose && !quiet {
-			fmt.Printf("%s Processing file %d/%d: %s\n",
-				cyan("↳"), i+1, len(paths), getRelativePath(path, baseDir))
-		} else if !quiet && len(paths) > 10 && (i+1)%int((len(paths)/10)+1) == 0 {
-			// Show progress for larger operations
-			progress := float64(i+1) / float64(len(paths)) * 100
-			fmt.Printf("%s Progress: %d/%d files (%.1f%%)\n",
-				cyan("→"), i+1, len(paths), progress)
+	if config.RespectEditorConfig {
+		config.editorConfigs = newEditorConfigCache(inputOpener(config))
+	}
+
+	if config.GitIgnore && config.InputDir != stdinInput {
+		config.gitignores = newGitignoreCache(config.InputDir, inputOpener(config), config.Verbose && !config.Quiet)
+	}
+
+	if config.ProgressFile != "" {
+		config.progress = newProgressReporter(config.ProgressFile)
+	}
+
+	if config.Timeout != "" {
+		d, err := time.ParseDuration(config.Timeout)
+		if err != nil || d <= 0 {
+			fmt.Printf("%s Invalid -timeout %q: use a positive duration such as 30s or 2m\n", red("✗"), config.Timeout)
+			os.Exit(1)
 		}
+		config.timeout = d
+	}
 
-		info, err := processSingleFile(path, baseDir)
+	if config.JSONPrettyThreshold != "" {
+		maxBytes, maxFiles, err := parseJSONPrettyThreshold(config.JSONPrettyThreshold)
 		if err != nil {
-			if !quiet {
-				fmt.Printf("%s Error processing %s: %v\n", red("✗"), path, err)
-			}
-			continue
+			fmt.Printf("%s Invalid -json-pretty-threshold: %v\n", red("✗"), err)
+			os.Exit(1)
 		}
+		config.jsonPrettyMaxBytes, config.jsonPrettyMaxFiles = maxBytes, maxFiles
+	}
 
-		fileInfos = append(fileInfos, info)
-		stats.FilesProcessed++
-		stats.TotalBytes += info.Size
+	if config.QuarantineOversize && config.MaxFileSize <= 0 {
+		fmt.Printf("%s -quarantine-oversize needs a -max-size limit\n", red("✗"))
+		os.Exit(1)
+	}
 
-		if verbose && !quiet && (i+1)%10 == 0 {
-			fmt.Printf("%s Processed %d/%d files\n", cyan("→"), i+1, len(paths))
-		}
+	if err := validateDedupByName(config.DedupByName); err != nil {
+		fmt.Printf("%s Invalid -dedup-by-name: %v\n", red("✗"), err)
+		os.Exit(1)
 	}
 
-	return fileInfos
-}
+	if err := validateReadOrder(config.ReadOrder); err != nil {
+		fmt.Printf("%s Invalid -read-order: %v\n", red("✗"), err)
+		os.Exit(1)
+	}
 
-func processFilesParallel(paths []string, baseDir string, workers int, verbose, quiet bool, stats *Stats) []FileInfo {
-	var wg sync.WaitGroup
-	fileChan := make(chan string, len(paths))
-	resultChan := make(chan FileInfo, len(paths))
-	errorChan := make(chan error, len(paths))
+	// -parallel 0, the default, uses every CPU; 1 stays strictly sequential
+	if config.Parallel < 0 {
+		fmt.Printf("%s -parallel must be 0 (all CPUs) or a positive number of workers\n", red("✗"))
+		os.Exit(1)
+	}
+	if config.Parallel == 0 {
+		config.Parallel = runtime.NumCPU()
+	}
 
-	var processed int32
-	totalFiles := len(paths)
+	if config.ReadRateLimit < 0 {
+		fmt.Printf("%s -read-rate-limit must not be negative\n", red("✗"))
+		os.Exit(1)
+	}
+	if config.ReadRateLimit > 0 {
+		config.readLimiter = newRateLimiter(config.ReadRateLimit)
+	}
 
-	// Start worker goroutines
-	for i := 0; i < workers; i++ {
-		wg.Add(1)
+	if config.SymlinkMetadata {
+		config.ExcludeSymlinks = true
+	}
+	if config.MaterializeSymlinks {
+		if config.ExcludeSymlinks {
+			fmt.Printf("%s -materialize-symlinks cannot be combined with -exclude-symlinks or -include-symlink-targets-as-metadata\n", red("✗"))
+			os.Exit(1)
+		}
+		config.FollowSymlinks = true
+	}
+
+	if config.ContentHashOnly {
+		switch {
+		case config.OutputFormat != "text" && config.OutputFormat != "json" && config.OutputFormat != "jsonl":
+			fmt.Printf("%s -content-hash-only writes text (sha256sum lines) or json, not %s\n", red("✗"), config.OutputFormat)
+			os.Exit(1)
+		case config.ContentGrep != "" || config.DetectSecrets || config.OutputDirMirror != "" || splitsOutput(config):
+			fmt.Printf("%s -content-hash-only keeps no content, so it cannot be combined with -content-grep, -detect-secrets, -output-dir-mirror, -chunk-by-tokens or -split-size\n", red("✗"))
+			os.Exit(1)
+		}
+		config.Hash = true
+	}
+
+	if config.GitAuthor && config.InputDir != stdinInput {
+		// git runs locally, so a remote input has no history to consult
+		config.gitRepo = config.inputFS == nil && insideGitRepo(config.InputDir)
+		if !config.gitRepo && !config.Quiet {
+			fmt.Printf("%s %s is not in a git repository; -git-author has no effect\n", yellow("⚠"), config.InputDir)
+		}
+	}
+
+	// Load the previous bundle up front so a bad path fails fast
+	var previous []FileInfo
+	if config.DiffAgainst != "" {
+		prev, err := loadPreviousBundle(config.DiffAgainst)
+		if err != nil {
+			fmt.Printf("%s Error loading -diff-against bundle: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		previous = prev
+		config.Hash = true
+	}
+	if config.ReportDuplicates || config.Manifest != "" || config.VerifyManifest != "" {
+		config.Hash = true
+	}
+
+	// Validate the output mtime before doing any work
+	if config.OutputMtime != "" && config.OutputMtime != "newest" {
+		if _, err := parseTimestamp(config.OutputMtime); err != nil {
+			fmt.Printf("%s Invalid -touch-output-mtime value: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+	}
+
+	// Validate patterns
+	var excludeRegex, includeRegex *regexp.Regexp
+	if config.ExcludePattern != "" {
+		re, err := regexp.Compile(config.ExcludePattern)
+		if err != nil {
+			fmt.Printf("%s Invalid exclude pattern: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		excludeRegex = re
+	}
+	if config.IncludePattern != "" {
+		re, err := regexp.Compile(config.IncludePattern)
+		if err != nil {
+			fmt.Printf("%s Invalid include pattern: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		includeRegex = re
+	}
+	if len(config.ExcludeGlobs) > 0 {
+		re, err := compileGlobs(config.ExcludeGlobs)
+		if err != nil {
+			fmt.Printf("%s Invalid exclude glob: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		config.excludeGlob = re
+	}
+	if len(config.IncludeGlobs) > 0 {
+		re, err := compileGlobs(config.IncludeGlobs)
+		if err != nil {
+			fmt.Printf("%s Invalid include glob: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		config.includeGlob = re
+	}
+	if config.ContentGrep != "" {
+		re, err := regexp.Compile(config.ContentGrep)
+		if err != nil {
+			fmt.Printf("%s Invalid content-grep pattern: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		config.contentRegex = re
+	}
+	if config.AllowList != "" {
+		paths, err := loadPathList(config.AllowList)
+		if err != nil {
+			fmt.Printf("%s Error loading -allow-list: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		config.allowPaths = paths
+	}
+	if config.DenyList != "" {
+		paths, err := loadPathList(config.DenyList)
+		if err != nil {
+			fmt.Printf("%s Error loading -deny-list: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		config.denyPaths = paths
+	}
+	if config.OrderFile != "" {
+		order, err := loadOrderFile(config.OrderFile)
+		if err != nil {
+			fmt.Printf("%s Error loading -order-file: %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		config.fileOrder = order
+	}
+	if config.SnippetLines > 0 && config.contentRegex == nil {
+		fmt.Printf("%s -snippet-lines requires -content-grep\n", red("✗"))
+		os.Exit(1)
+	}
+
+	if *filterTest {
+		if config.InputDir == stdinInput {
+			fmt.Printf("%s -interactive-filter-test needs an input directory\n", red("✗"))
+			os.Exit(1)
+		}
+		excludeRegex, includeRegex = runFilterTest(&config)
+		fmt.Println()
+	}
+
+	if *scanExts {
+		if config.InputDir == stdinInput {
+			fmt.Printf("%s -scan-extensions needs an input directory\n", red("✗"))
+			os.Exit(1)
+		}
+		counts, err := scanExtensions(config, excludeRegex, includeRegex)
+		if err != nil {
+			fmt.Printf("%s Error %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		printExtensions(os.Stdout, counts)
+		return
+	}
+
+	if config.VerifyManifest != "" {
+		if config.InputDir == stdinInput {
+			fmt.Printf("%s -verify-against-manifest needs an input directory\n", red("✗"))
+			os.Exit(1)
+		}
+		drift, err := verifyManifest(config, excludeRegex, includeRegex)
+		if err != nil {
+			fmt.Printf("%s Error %v\n", red("✗"), err)
+			os.Exit(1)
+		}
+		if drift {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !config.Quiet {
+		fmt.Printf("%s Starting Pecel v%s\n", cyan("→"), version)
+		if config.remoteInput != "" {
+			fmt.Printf("%s Input directory: %s\n", cyan("→"), config.remoteInput)
+		} else {
+			fmt.Printf("%s Input directory: %s\n", cyan("→"), config.InputDir)
+		}
+		if config.jsonStream != nil {
+			fmt.Printf("%s Output: JSON lines on stdout\n", cyan("→"))
+		} else {
+			switch {
+			case config.OutputFile == stdoutOutput:
+				fmt.Printf("%s Output: stdout\n", cyan("→"))
+			case splitsOutput(config):
+				// The parts are named after -output, which is not written itself
+				fmt.Printf("%s Output files: %s, %s, ...\n", cyan("→"),
+					partPath(config.OutputFile, 1), partPath(config.OutputFile, 2))
+			default:
+				fmt.Printf("%s Output file: %s\n", cyan("→"), config.OutputFile)
+			}
+		}
+		if config.Verbose {
+			fmt.Printf("%s Workers: %d\n", cyan("→"), config.Parallel)
+		}
+		if config.DryRun {
+			fmt.Printf("%s DRY RUN MODE - No files will be written\n", yellow("⚠"))
+		}
+	}
+
+	ctx, cancel := newRunContext(config.timeout)
+	defer cancel()
+	fileInfos, err := run(ctx, config, excludeRegex, includeRegex, previous, startTime)
+	if err != nil {
+		if config.inputFS != nil {
+			config.inputFS.Close()
+		}
+		if code, canceled := canceledExitCode(err); canceled {
+			os.Exit(code)
+		}
+		fmt.Printf("%s Error %v\n", red("✗"), err)
+		os.Exit(1)
+	}
+
+	if config.Watch {
+		if err := watch(ctx, config, excludeRegex, includeRegex, previous, fileInfos); err != nil {
+			if code, canceled := canceledExitCode(err); canceled {
+				os.Exit(code)
+			}
+			fmt.Printf("%s Error watching %s: %v\n", red("✗"), config.InputDir, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// run collects, processes and writes one bundle, then prints the summary. It
+// returns the processed files so watch mode can tell later changes apart.
+// When ctx is canceled, reading stops and the output is written with the
+// files processed so far; run then returns ctx's error.
+func run(ctx context.Context, config Config, excludeRegex, includeRegex *regexp.Regexp, previous []FileInfo, startTime time.Time) (_ []FileInfo, err error) {
+	if config.jsonStream != nil && !config.DryRun {
+		return nil, runStream(ctx, config, excludeRegex, includeRegex, startTime)
+	}
+
+	// Collect file information
+	var fileInfos []FileInfo
+	var stats Stats
+	defer func() { recordMetrics(config, &stats, startTime, err) }()
+
+	// A streamed run writes the output while reading and keeps only metadata
+	streamed := config.InputDir != stdinInput && canStreamOutput(config)
+	var streamedSize, streamedUncompressed int64
+
+	if config.InputDir == stdinInput {
+		phaseStart := time.Now()
+		info, err := readStdinInput(config)
+		if err != nil {
+			return nil, fmt.Errorf("reading stdin: %w", err)
+		}
+		fileInfos = []FileInfo{info}
+		stats.FilesProcessed = 1
+		stats.TotalBytes = info.Size
+		stats.TotalLines = info.LineCount
+		stats.ProcessDuration = time.Since(phaseStart).Seconds()
+	} else {
+		// Walk directory to collect files
+		phaseStart := time.Now()
+		filePaths, err := collectFiles(ctx, config, excludeRegex, includeRegex, &stats)
+		if err != nil {
+			return nil, fmt.Errorf("walking directory: %w", err)
+		}
+		stats.WalkDuration = time.Since(phaseStart).Seconds()
+
+		if !config.Quiet {
+			fmt.Printf("%s Found %d files to process\n", cyan("→"), len(filePaths))
+		}
+
+		// Process files; when streamed, reading and writing overlap and are
+		// reported as one phase
+		phaseStart = time.Now()
+		config.progress.begin(len(filePaths))
+		if streamed {
+			header := stats
+			header.FilesProcessed, header.TotalBytes = len(filePaths), walkedBytes(filePaths)
+			header.Duration = time.Since(startTime).Seconds()
+			fileInfos, streamedSize, streamedUncompressed, err = writeStreamedOutput(ctx, filePaths, config, &stats, header)
+		} else if config.Parallel > 1 {
+			fileInfos = processFilesParallel(ctx, filePaths, config, &stats)
+		} else {
+			fileInfos = processFilesSequential(ctx, filePaths, config, &stats)
+		}
+		config.progress.finish()
+		stats.ProcessDuration = time.Since(phaseStart).Seconds()
+		if err != nil {
+			return nil, fmt.Errorf("writing output: %w", err)
+		}
+	}
+
+	if config.fileOrder != nil {
+		applyOrder(fileInfos, config.fileOrder, config)
+	}
+
+	if config.DiffAgainst != "" {
+		stats.Diff = diffBundles(fileInfos, previous)
+	}
+	if config.ReportDuplicates {
+		stats.Duplicates = findDuplicates(fileInfos)
+	}
+	if config.GroupSummary {
+		stats.Groups = groupByTopDir(fileInfos, config)
+	}
+	if config.DetectSecrets {
+		if err := checkSecrets(fileInfos, config); err != nil {
+			return fileInfos, err
+		}
+	}
+
+	stats.Duration = time.Since(startTime).Seconds()
+
+	if config.NumberFiles {
+		numberFiles(fileInfos, &config)
+	}
+
+	// Generate output
+	if !config.DryRun {
+		phaseStart := time.Now()
+		if config.ExternalizeContent != "" {
+			blobs, err := externalizeContent(fileInfos, config)
+			if err != nil {
+				return fileInfos, fmt.Errorf("externalizing content: %w", err)
+			}
+			stats.ContentBlobs = blobs
+		}
+		outputs := []string{config.OutputFile}
+		var outputSize, uncompressedSize int64
+		var err error
+		if streamed {
+			outputSize, uncompressedSize = streamedSize, streamedUncompressed
+		} else if config.OutputDirMirror != "" {
+			outputs = nil
+			outputSize, err = writeMirror(fileInfos, config)
+			uncompressedSize = outputSize
+		} else if config.OutputFormat == "sqlite" {
+			outputSize, err = writeSQLiteOutput(fileInfos, config)
+			uncompressedSize = outputSize
+		} else if config.splitBytes > 0 {
+			chunks, oversized := chunkBySize(fileInfos, config.splitBytes)
+			for _, path := range oversized {
+				fmt.Printf("%s %s exceeds %s and was placed in its own part\n",
+					yellow("⚠"), path, formatBytes(config.splitBytes))
+			}
+			outputs, stats.ResumedParts, outputSize, uncompressedSize, err = writeChunkedOutput(chunks, config, stats)
+			stats.OutputParts = outputs
+		} else if config.ChunkTokens > 0 {
+			chunks, oversized := chunkByTokens(fileInfos, config.ChunkTokens)
+			for _, path := range oversized {
+				fmt.Printf("%s %s exceeds %d estimated tokens and was placed in its own part\n",
+					yellow("⚠"), path, config.ChunkTokens)
+			}
+			outputs, stats.ResumedParts, outputSize, uncompressedSize, err = writeChunkedOutput(chunks, config, stats)
+			stats.OutputParts = outputs
+		} else {
+			outputSize, uncompressedSize, err = writeOutput(fileInfos, config, stats)
+		}
+		if err != nil {
+			return fileInfos, fmt.Errorf("writing output: %w", err)
+		}
+		stats.OutputSize = outputSize
+		stats.UncompressedSize = uncompressedSize
+
+		if config.Manifest != "" {
+			if err := writeManifest(config.Manifest, fileInfos); err != nil {
+				return fileInfos, fmt.Errorf("writing manifest: %w", err)
+			}
+		}
+
+		if config.OutputMtime != "" {
+			for _, path := range outputs {
+				if err := touchOutput(path, config.OutputMtime, fileInfos); err != nil {
+					return fileInfos, fmt.Errorf("setting output modification time: %w", err)
+				}
+			}
+		}
+		stats.WriteDuration = time.Since(phaseStart).Seconds()
+		// The output itself records the time up to writing; the summary
+		// reports the whole run
+		stats.Duration = time.Since(startTime).Seconds()
+	}
+
+	// Print summary
+	printSummary(stats, config)
+	if config.GroupSummary {
+		printGroups(stats.Groups, stats.TotalBytes)
+	}
+	if config.QuarantineOversize {
+		printOversize(stats.Oversize, config.MaxFileSize)
+	}
+	if config.ReportDuplicates {
+		printDuplicates(stats.Duplicates)
+	}
+
+	if ctx.Err() != nil {
+		return fileInfos, reportCanceled(ctx, stats)
+	}
+	if config.DryRun {
+		fmt.Printf("\n%s Dry run completed. %d files would be processed.\n",
+			green("✓"), stats.FilesProcessed)
+	} else {
+		fmt.Printf("\n%s Processing completed successfully!\n", green("✓"))
+	}
+	return fileInfos, nil
+}
+
+// stdinInput is the -input value that reads content from standard input.
+const stdinInput = "-"
+
+// readStdinInput reads all of stdin as a single synthetic file named "stdin",
+// so piped content gets the same formatting and transforms as a walked file.
+func readStdinInput(config Config) (FileInfo, error) {
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	now := time.Now()
+	info := FileInfo{
+		Path:         stdinInput,
+		RelativePath: labelPath("stdin", config),
+		Size:         int64(len(content)),
+		Modified:     now.Format("2006-01-02 15:04:05"),
+		modTime:      now,
+	}
+	if config.Hash {
+		sum := sha256.Sum256(content)
+		info.Hash = hex.EncodeToString(sum[:])
+	}
+	if !config.KeepBOM {
+		content = stripBOM(content)
+	}
+	setContent(&info, content, config)
+	if config.DetectType {
+		info.ContentType = detectContentType(content)
+	}
+	applyTransforms(&info, config)
+	return info, nil
+}
+
+// hasLineCount reports whether info's header shows a line count: it was
+// asked for and the entry has text content to count.
+func hasLineCount(info FileInfo, config Config) bool {
+	return config.LineCounts && info.Encoding == "" && !info.ContentOmitted && info.LinkTarget == ""
+}
+
+// walkEntry is a file selected by the walk, with the metadata recorded when
+// it was seen.
+type walkEntry struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// collectFiles walks config.InputDir and returns the paths of the files that
+// pass every filter, counting directories and skipped files in stats. When
+// ctx is canceled the walk stops and the files found so far are returned.
+func collectFiles(ctx context.Context, config Config, excludeRegex, includeRegex *regexp.Regexp, stats *Stats) ([]walkEntry, error) {
+	var filePaths []walkEntry
+
+	// Track include/exclude outcomes to explain an empty result
+	var includeMisses, includeExcluded int
+
+	// Real paths of the directories entered so far through symlinks
+	followed := make(map[string]bool)
+	if real, err := filepath.EvalSymlinks(config.InputDir); err == nil {
+		followed[real] = true
+	}
+
+	visitEntry := func(path string, info os.FileInfo) error {
+		if info.IsDir() {
+			stats.Directories++
+			// The root itself is never hidden, even when given as "." or "..".
+			if path != config.InputDir && config.ExcludeHidden && isHidden(info.Name()) {
+				return filepath.SkipDir
+			}
+			// Don't walk into a mirror directory nested in the input
+			if path != config.InputDir && isOwnArtifact(path, config) {
+				return filepath.SkipDir
+			}
+			if path != config.InputDir && config.ExcludeVendored && vendoredDirs[info.Name()] {
+				if config.Verbose && !config.Quiet {
+					fmt.Printf("%s Skipping vendored directory: %s\n", cyan("↳"), path)
+				}
+				return filepath.SkipDir
+			}
+			if path != config.InputDir && config.gitignores != nil && config.gitignores.ignored(path, true) {
+				if config.Verbose && !config.Quiet {
+					fmt.Printf("%s Skipping ignored directory: %s\n", cyan("↳"), path)
+				}
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Apply filters
+		switch fileSkipReason(path, info, config, excludeRegex, includeRegex) {
+		case skipNone:
+		case skipOwnArtifact:
+			if config.Verbose && !config.Quiet {
+				fmt.Printf("%s Skipping pecel output: %s\n", cyan("↳"), path)
+			}
+			return nil
+		case skipSpecialFile:
+			stats.SpecialSkipped++
+			if config.Verbose && !config.Quiet {
+				fmt.Printf("%s Skipping special file: %s\n", yellow("⚠"), path)
+			}
+			return nil
+		case skipIncludePattern:
+			includeMisses++
+			return nil
+		case skipSymlink:
+			if !config.SymlinkMetadata {
+				return nil
+			}
+		case skipOversize:
+			if config.QuarantineOversize {
+				stats.Oversize = append(stats.Oversize, OversizeFile{
+					Path: labelPath(getRelativePath(path, config.InputDir), config),
+					Size: info.Size(),
+				})
+			}
+			return nil
+		case skipBinary:
+			stats.BinariesSkipped++
+			if config.Verbose && !config.Quiet {
+				fmt.Printf("%s Skipping binary file: %s\n", cyan("↳"), path)
+			}
+			return nil
+		case skipLargeBinary:
+			stats.LargeBinarySkipped = append(stats.LargeBinarySkipped, path)
+			if !config.Quiet {
+				fmt.Printf("%s Skipping large binary file (%s): %s\n", yellow("⚠"), formatBytes(info.Size()), path)
+			}
+			return nil
+		case skipExcludePattern:
+			if includeRegex != nil && includeRegex.MatchString(getRelativePath(path, config.InputDir)) {
+				includeExcluded++
+			}
+			return nil
+		default:
+			return nil
+		}
+
+		// Record what will be read: a symlink's target, not the link itself
+		size, modTime := info.Size(), info.ModTime()
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, err := statInput(path, config); err == nil {
+				size, modTime = target.Size(), target.ModTime()
+			}
+		}
+		filePaths = append(filePaths, walkEntry{Path: path, Size: size, ModTime: modTime})
+		return nil
+	}
+
+	// hops is the number of symlinks followed to reach the tree being walked
+	var visit func(hops int) filepath.WalkFunc
+	visit = func(hops int) filepath.WalkFunc {
+		return func(path string, info os.FileInfo, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if isVanished(path, err, config) {
+				stats.Vanished++
+				reportVanished(path, config)
+				return nil
+			}
+			if isPathTooLong(err) {
+				stats.PathTooLong++
+				reportPathTooLong(path, config)
+				return nil
+			}
+			if err != nil {
+				if !config.Quiet {
+					fmt.Printf("%s Error accessing %s: %v\n", red("✗"), path, err)
+				}
+				return nil
+			}
+
+			// Symlinks to directories are only entered with -follow-symlinks,
+			// and only in a local tree
+			if info.Mode()&os.ModeSymlink != 0 && !config.ExcludeSymlinks {
+				target, err := statInput(path, config)
+				if err == nil && target.IsDir() {
+					if config.FollowSymlinks && config.inputFS == nil {
+						if linkHops, ok := followSymlinkDir(path, hops, followed, config); ok {
+							// The trailing separator makes Walk enter the link
+							filepath.Walk(path+string(filepath.Separator), visit(hops+linkHops))
+						}
+					}
+					return nil
+				}
+				limit := maxSymlinkDepth(config)
+				if linkHops, _ := symlinkHops(path, limit, config); hops+linkHops > limit {
+					if !config.Quiet {
+						fmt.Printf("%s Skipping %s: more than %d symlink hops (-max-symlink-depth)\n",
+							yellow("⚠"), path, limit)
+					}
+					return nil
+				}
+				// Filter a materialized link by what it copies, not the link
+				if err == nil && config.MaterializeSymlinks {
+					info = target
+				}
+			}
+
+			return visitEntry(path, info)
+		}
+	}
+
+	var err error
+	if config.WalkParallel > 1 {
+		err = parallelWalk(config.InputDir, config.WalkParallel, config, visit(0))
+		sortWalkOrder(filePaths)
+	} else {
+		err = walkInput(config.InputDir, config, visit(0))
+	}
+
+	if err != nil && ctx.Err() == nil {
+		return filePaths, err
+	}
+
+	sortOversize(stats.Oversize)
+	if config.DedupByName != "" {
+		filePaths, stats.DroppedByName = dedupByName(filePaths, config.DedupByName)
+	}
+
+	if includeRegex != nil && len(filePaths) == 0 {
+		switch {
+		case includeExcluded > 0:
+			fmt.Printf("%s All %d files matching -include %q are also matched by -exclude %q\n",
+				yellow("⚠"), includeExcluded, config.IncludePattern, config.ExcludePattern)
+		case includeMisses > 0:
+			fmt.Printf("%s -include %q matched none of the %d candidate files; the include pattern filtered everything out\n",
+				yellow("⚠"), config.IncludePattern, includeMisses)
+		}
+	}
+
+	return filePaths, nil
+}
+
+// skipReason explains why a walked file is left out of the output.
+type skipReason int
+
+const (
+	skipNone skipReason = iota
+	skipOwnArtifact
+	skipSpecialFile
+	skipHidden
+	skipGitignore
+	skipSize
+	skipOversize
+	skipAge
+	skipExtension
+	skipTestFile
+	skipExcludePattern
+	skipIncludePattern
+	skipDenyList
+	skipAllowList
+	skipSymlink
+	skipLargeBinary
+	skipBinary
+)
+
+func shouldProcessFile(path string, info os.FileInfo, config Config,
+	excludeRegex, includeRegex *regexp.Regexp) bool {
+	return fileSkipReason(path, info, config, excludeRegex, includeRegex) == skipNone
+}
+
+// fileSkipReason applies the configured filters to a walked file and returns
+// the first one that rejects it, or skipNone if the file should be processed.
+func fileSkipReason(path string, info os.FileInfo, config Config,
+	excludeRegex, includeRegex *regexp.Regexp) skipReason {
+
+	// Never bundle the output or sidecars this run produces
+	if isOwnArtifact(path, config) {
+		return skipOwnArtifact
+	}
+
+	// Skip pipes, sockets and devices; reading them can block forever
+	if isSpecialFile(info) {
+		return skipSpecialFile
+	}
+
+	// Skip hidden files
+	if config.ExcludeHidden && isHidden(info.Name()) {
+		return skipHidden
+	}
+
+	// Skip files matched by the .gitignore files in the tree
+	if config.gitignores != nil && config.gitignores.ignored(path, info.IsDir()) {
+		return skipGitignore
+	}
+
+	// Check file size limits
+	if config.MaxFileSize > 0 && info.Size() > config.MaxFileSize {
+		return skipOversize
+	}
+	if config.MinFileSize > 0 && info.Size() < config.MinFileSize {
+		return skipSize
+	}
+
+	// Check modification age
+	if !config.modifiedAfter.IsZero() && info.ModTime().Before(config.modifiedAfter) {
+		return skipAge
+	}
+	if !config.modifiedBefore.IsZero() && !info.ModTime().Before(config.modifiedBefore) {
+		return skipAge
+	}
+
+	// Check extensions
+	if len(config.Extensions) > 0 {
+		ext := filepath.Ext(path)
+		found := false
+		for _, allowedExt := range config.Extensions {
+			if strings.EqualFold(ext, allowedExt) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return skipExtension
+		}
+	}
+
+	// Exact paths are checked before the patterns, and win over them
+	relPath, _ := filepath.Rel(config.InputDir, path)
+	if config.ExcludeTests && isTestFile(relPath) {
+		return skipTestFile
+	}
+	if config.denyPaths[relPath] {
+		return skipDenyList
+	}
+	if config.allowPaths != nil && !config.allowPaths[relPath] {
+		return skipAllowList
+	}
+
+	// Check regex and glob patterns
+	if excludeRegex != nil && excludeRegex.MatchString(relPath) {
+		return skipExcludePattern
+	}
+	if config.excludeGlob != nil && config.excludeGlob.MatchString(filepath.ToSlash(relPath)) {
+		return skipExcludePattern
+	}
+	if includeRegex != nil && !includeRegex.MatchString(relPath) {
+		return skipIncludePattern
+	}
+	if config.includeGlob != nil && !config.includeGlob.MatchString(filepath.ToSlash(relPath)) {
+		return skipIncludePattern
+	}
+
+	if config.ExcludeSymlinks && info.Mode()&os.ModeSymlink != 0 {
+		return skipSymlink
+	}
+
+	// Last, since it has to read the file, which both checks share: keep
+	// databases, videos and other large binaries from blowing up the output,
+	// and binaries that are not hex dumped or base64-encoded out of it
+	largeBinary := !config.KeepLargeBinaries && info.Size() > largeBinaryThreshold(config)
+	anyBinary := !config.KeepBinaries && !config.HexdumpBinary && !config.Base64Binary
+	if (largeBinary || anyBinary) && looksBinary(path, config) {
+		if largeBinary {
+			return skipLargeBinary
+		}
+		return skipBinary
+	}
+
+	return skipNone
+}
+
+// ownArtifactPaths lists every file a run with this configuration writes: the
+// output itself and the sidecars derived from it.
+func ownArtifactPaths(config Config) []string {
+	paths := []string{config.OutputFile, config.OutputFile + ".gz"}
+	if config.OutputDirMirror != "" {
+		paths = append(paths, config.OutputDirMirror)
+	}
+	if config.ExternalizeContent != "" {
+		paths = append(paths, config.ExternalizeContent)
+	}
+	if splitsOutput(config) {
+		manifest := partsManifestPath(config.OutputFile)
+		paths = append(paths, manifest, manifest+partialSuffix)
+	}
+	if config.ProgressFile != "" {
+		paths = append(paths, config.ProgressFile)
+	}
+	if config.MetricsFile != "" {
+		paths = append(paths, config.MetricsFile)
+	}
+	for _, manifest := range []string{config.Manifest, config.VerifyManifest} {
+		if manifest != "" {
+			paths = append(paths, manifest)
+		}
+	}
+	return paths
+}
+
+// resolveOwnArtifacts records the absolute form of ownArtifactPaths so the
+// walk can compare paths rather than file names.
+func resolveOwnArtifacts(config *Config) {
+	config.ownArtifacts = make(map[string]bool)
+	for _, p := range ownArtifactPaths(*config) {
+		if abs, err := filepath.Abs(p); err == nil {
+			config.ownArtifacts[abs] = true
+		}
+	}
+}
+
+func isOwnArtifact(path string, config Config) bool {
+	// pecel only writes locally, never into a remote input tree
+	if len(config.ownArtifacts) == 0 || config.inputFS != nil {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if config.ownArtifacts[abs] {
+		return true
+	}
+	// Part files are numbered, so they are matched against the output path.
+	if splitsOutput(config) {
+		if output, err := filepath.Abs(config.OutputFile); err == nil && isPartOf(abs, output) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSpecialFile reports whether info describes a named pipe, socket or device.
+// Symlinks are not special: they are resolved when the file is read.
+func isSpecialFile(info os.FileInfo) bool {
+	return info.Mode()&(os.ModeNamedPipe|os.ModeSocket|os.ModeDevice|os.ModeCharDevice|os.ModeIrregular) != 0
+}
+
+func processFilesSequential(ctx context.Context, entries []walkEntry, config Config, stats *Stats) []FileInfo {
+	var fileInfos []FileInfo
+	baseDir, verbose, quiet := config.InputDir, config.Verbose, config.Quiet
+	config.console = startConsole()
+	defer config.console.close()
+	bar := startProgressBar(len(entries), config)
+
+	for i, walkIndex := range readOrder(entries, config.ReadOrder) {
+		if ctx.Err() != nil {
+			break
+		}
+		entry := entries[walkIndex]
+		path := entry.Path
+		if verbose && !quiet {
+			config.console.printf("%s Processing file %d/%d: %s\n",
+				cyan("↳"), i+1, len(entries), getRelativePath(path, baseDir))
+		} else if bar == nil && !quiet && len(entries) > 10 && (i+1)%int((len(entries)/10)+1) == 0 {
+			// Show progress for larger operations
+			progress := float64(i+1) / float64(len(entries)) * 100
+			config.console.printf("%s Progress: %d/%d files (%.1f%%)\n",
+				cyan("→"), i+1, len(entries), progress)
+		}
+
+		info, err := processSingleFile(path, config)
+		config.progress.fileDone(info.Size)
+		bar.add()
+		if errors.Is(err, errNoContentMatch) {
+			continue
+		}
+		if isVanished(path, err, config) {
+			stats.Vanished++
+			reportVanished(path, config)
+			continue
+		}
+		if isPathTooLong(err) {
+			stats.PathTooLong++
+			reportPathTooLong(path, config)
+			continue
+		}
+		if err != nil {
+			stats.Errors++
+			if !quiet {
+				config.console.printf("%s Error processing %s: %v\n", red("✗"), path, err)
+			}
+			continue
+		}
+
+		reportWalkDrift(entry, info, config)
+		info.walkIndex = walkIndex
+		fileInfos = append(fileInfos, info)
+		stats.FilesProcessed++
+		stats.TotalBytes += info.Size
+		stats.TotalLines += info.LineCount
+
+		if verbose && !quiet && (i+1)%10 == 0 {
+			config.console.printf("%s Processed %d/%d files\n", cyan("→"), i+1, len(entries))
+		}
+	}
+
+	// The output follows the walk, whatever order the files were read in
+	sort.Slice(fileInfos, func(i, j int) bool {
+		return fileInfos[i].walkIndex < fileInfos[j].walkIndex
+	})
+	return fileInfos
+}
+
+func processFilesParallel(ctx context.Context, entries []walkEntry, config Config, stats *Stats) []FileInfo {
+	workers, verbose, quiet := config.Parallel, config.Verbose, config.Quiet
+	var wg sync.WaitGroup
+	fileChan := make(chan int, len(entries))
+	resultChan := make(chan FileInfo, len(entries))
+	errorChan := make(chan error, len(entries))
+
+	// Workers only touch these counters and the channels; stats belongs to
+	// this goroutine and is updated once they are done
+	var processed, vanished, tooLong int32
+	totalFiles := len(entries)
+	config.console = startConsole()
+	bar := startProgressBar(totalFiles, config)
+
+	// Start worker goroutines
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			for path := range fileChan {
-				info, err := processSingleFile(path, baseDir)
+			for walkIndex := range fileChan {
+				// Once canceled, the queued files are dropped unread
+				if ctx.Err() != nil {
+					continue
+				}
+				entry := entries[walkIndex]
+				path := entry.Path
+				info, err := processSingleFile(path, config)
+				config.progress.fileDone(info.Size)
+
+				// Progress counts every file handled, whatever its outcome,
+				// so it ends at totalFiles
+				curr := atomic.AddInt32(&processed, 1)
+				bar.add()
+				if verbose && !quiet && curr%10 == 0 {
+					config.console.printf("%s Worker %d: Processed %d/%d files\n",
+						cyan("→"), workerID, curr, totalFiles)
+				} else if bar == nil && !verbose && !quiet && totalFiles > 10 && int(curr)%((totalFiles/10)+1) == 0 {
+					// Show overall progress for larger operations
+					progress := float64(curr) / float64(totalFiles) * 100
+					config.console.printf("%s Overall progress: %d/%d files (%.1f%%)\n",
+						cyan("→"), curr, totalFiles, progress)
+				}
+
+				if errors.Is(err, errNoContentMatch) {
+					continue
+				}
+				if isVanished(path, err, config) {
+					atomic.AddInt32(&vanished, 1)
+					reportVanished(path, config)
+					continue
+				}
+				if isPathTooLong(err) {
+					atomic.AddInt32(&tooLong, 1)
+					reportPathTooLong(path, config)
+					continue
+				}
 				if err != nil {
 					errorChan <- fmt.Errorf("%s: %v", path, err)
 					continue
 				}
+				reportWalkDrift(entry, info, config)
+				info.walkIndex = walkIndex
 				resultChan <- info
+			}
+		}(i)
+	}
+
+	// Send files to workers in -read-order
+	for _, i := range readOrder(entries, config.ReadOrder) {
+		fileChan <- i
+	}
+	close(fileChan)
+
+	// Wait for workers to finish
+	wg.Wait()
+	config.console.close()
+	close(resultChan)
+	close(errorChan)
+
+	// Collect results
+	var fileInfos []FileInfo
+	stats.Vanished += int(vanished)
+	stats.PathTooLong += int(tooLong)
+	for info := range resultChan {
+		fileInfos = append(fileInfos, info)
+		stats.FilesProcessed++
+		stats.TotalBytes += info.Size
+		stats.TotalLines += info.LineCount
+	}
+
+	// Report errors
+	for err := range errorChan {
+		stats.Errors++
+		if !quiet {
+			fmt.Printf("%s %v\n", red("✗"), err)
+		}
+	}
+
+	// Results arrive as workers finish; put them back in walk order so the
+	// output matches a sequential run
+	sort.Slice(fileInfos, func(i, j int) bool {
+		return fileInfos[i].walkIndex < fileInfos[j].walkIndex
+	})
+	return fileInfos
+}
+
+// reportWalkDrift flags, in verbose mode, files whose size when read differs
+// from the size recorded by the walk: they changed in between, so the walk's
+// view of the tree is stale for them. The size that was read is the one used.
+func reportWalkDrift(entry walkEntry, info FileInfo, config Config) {
+	if !config.Verbose || config.Quiet || info.LinkTarget != "" || info.Size == entry.Size {
+		return
+	}
+	config.console.printf("%s %s changed after the walk (%s when walked, %s when read)\n",
+		yellow("⚠"), entry.Path, formatBytes(entry.Size), formatBytes(info.Size))
+}
+
+// errNoContentMatch is returned by processSingleFile for files that -content-grep
+// filters out; it is not reported as a failure.
+var errNoContentMatch = errors.New("content does not match -content-grep")
+
+func processSingleFile(path string, config Config) (FileInfo, error) {
+	info := FileInfo{
+		Path:         path,
+		RelativePath: labelPath(getRelativePath(path, config.InputDir), config),
+	}
+
+	// Excluded symlinks only reach this point to be recorded, not read
+	if config.SymlinkMetadata {
+		if linkInfo, err := lstatInput(path, config); err == nil && linkInfo.Mode()&os.ModeSymlink != 0 {
+			info.LinkTarget, err = readInputLink(path, config)
+			info.modTime = linkInfo.ModTime()
+			info.Modified = info.modTime.Format("2006-01-02 15:04:05")
+			return info, err
+		}
+	}
+
+	// Get file stats
+	fileInfo, err := statInput(path, config)
+	if err != nil {
+		return info, err
+	}
+
+	info.Size = fileInfo.Size()
+	info.modTime = fileInfo.ModTime()
+	info.Modified = info.modTime.Format("2006-01-02 15:04:05")
+	if config.gitRepo {
+		info.LastAuthor, info.LastCommitDate = gitLastCommit(path)
+	}
+
+	// Deep files are only indexed
+	if config.ContentMaxDepth > 0 && pathDepth(getRelativePath(path, config.InputDir)) > config.ContentMaxDepth {
+		info.ContentOmitted = true
+		return info, nil
+	}
+
+	if config.ContentHashOnly {
+		info.Hash, err = hashFile(path, config)
+		return info, err
+	}
+
+	// Read file content, hashing it on the way in when requested
+	var content []byte
+	if config.Hash && config.readLimiter == nil {
+		content, info.Hash, err = readAndHash(path, info.Size, config)
+	} else {
+		content, err = readInputFile(path, info.Size, config)
+		if err == nil && config.Hash {
+			sum := sha256.Sum256(content)
+			info.Hash = hex.EncodeToString(sum[:])
+		}
+	}
+	if err != nil {
+		return info, err
+	}
+
+	// The file changed between the stat and the read; describe what was read
+	if n := int64(len(content)); n != info.Size {
+		if config.Verbose && !config.Quiet {
+			config.console.printf("%s %s changed while being read (%s stat'd, %s read)\n",
+				yellow("⚠"), path, formatBytes(info.Size), formatBytes(n))
+		}
+		info.Size = n
+	}
+
+	// Hashes and sizes describe the file on disk, so the BOM goes only now
+	if !config.KeepBOM {
+		content = stripBOM(content)
+	}
+
+	if config.contentRegex != nil && !config.contentRegex.Match(content) {
+		return info, errNoContentMatch
+	}
+
+	setContent(&info, content, config)
+	if config.DetectType {
+		info.ContentType = detectContentType(content)
+	}
+	applyTransforms(&info, config)
+	return info, nil
+}
+
+// outputFormat describes a -format value for -list-formats and the
+// interactive prompt.
+type outputFormat struct {
+	Name        string
+	Description string
+	Extension   string
+}
+
+// outputFormats lists the formats writeOutput can render, in the order they
+// are offered to users.
+var outputFormats = []outputFormat{
+	{"text", "Plain text with a banner and separator lines per file", ".txt"},
+	{"json", "JSON document with a files array and run metadata", ".json"},
+	{"xml", "XML document with one <file> element per file", ".xml"},
+	{"markdown", "Markdown with a heading and fenced code block per file (alias: md)", ".md"},
+	{"html-app", "Self-contained HTML page with a file tree, collapsible files and a search box (alias: html)", ".html"},
+	{"yaml", "YAML document with the same files and metadata as json (alias: yml)", ".yaml"},
+	{"csv", "One row per file: path, relative path, size, modified time and lines", ".csv"},
+	{"sqlite", "SQLite database with one row per file in a files table", ".db"},
+}
+
+// printFormats writes the -list-formats table.
+func printFormats(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FORMAT\tEXTENSION\tDESCRIPTION")
+	for _, f := range availableFormats() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Name, f.Extension, f.Description)
+	}
+	tw.Flush()
+}
+
+// parallelGzipThreshold is the input size below which output is compressed
+// on a single goroutine; smaller bundles finish before extra workers pay off.
+const parallelGzipThreshold = 8 * 1024 * 1024
+
+// parallelGzipBlockSize is the amount of output each pgzip worker compresses
+// at a time.
+const parallelGzipBlockSize = 1 << 20
+
+// newGzipWriter returns the gzip compressor for the output. Large bundles are
+// compressed in parallel blocks with pgzip, using -compress-workers
+// goroutines (or -parallel when unset); the result is still a standard gzip
+// stream.
+func newGzipWriter(w io.Writer, config Config, stats Stats) (io.WriteCloser, error) {
+	workers := config.CompressWorkers
+	if workers == 0 {
+		workers = config.Parallel
+	}
+	level := config.CompressionLevel
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	if workers <= 1 || stats.TotalBytes < parallelGzipThreshold {
+		return gzip.NewWriterLevel(w, level)
+	}
+	gz, err := pgzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	if err := gz.SetConcurrency(parallelGzipBlockSize, workers); err != nil {
+		return nil, err
+	}
+	return gz, nil
+}
+
+// writeOutput renders fileInfos to the configured output file or remote sink
+// and returns the number of bytes written to it together with the
+// uncompressed size of the rendered document. The two only differ when
+// compression is enabled.
+func writeOutput(fileInfos []FileInfo, config Config, stats Stats) (int64, int64, error) {
+	return writeOutputFile(config, stats, func(rendered io.Writer, config Config) error {
+		var err error
+		switch strings.ToLower(config.OutputFormat) {
+		case "json":
+			if config.ContentHashOnly {
+				_, err = writeHashMap(fileInfos, rendered)
+				break
+			}
+			_, err = writeJSONOutput(fileInfos, rendered, config, stats)
+		case "xml":
+			_, err = writeXMLOutput(fileInfos, rendered, config, stats)
+		case "markdown", "md":
+			_, err = writeMarkdownOutput(fileInfos, rendered, config, stats)
+		case "html-app", "html":
+			_, err = writeHTMLAppOutput(fileInfos, rendered, config, stats)
+		case "csv":
+			_, err = writeCSVOutput(fileInfos, rendered, config)
+		case "yaml", "yml":
+			_, err = writeYAMLOutput(fileInfos, rendered, config, stats)
+		default:
+			if writer, ok := pecel.LookupFormat(config.OutputFormat); ok {
+				_, err = writeRegisteredFormat(writer, fileInfos, rendered, stats)
+			} else if config.ContentHashOnly {
+				_, err = io.WriteString(rendered, formatManifest(fileInfos))
+			} else { // text
+				_, err = writeTextOutput(fileInfos, rendered, config, stats)
+			}
+		}
+		return err
+	})
+}
+
+// writeOutputFile opens the configured output, compressing it if requested,
+// and has render write the document. config is passed on with the separator
+// width for the destination filled in. It returns the bytes written to the
+// output and the uncompressed size, like writeOutput.
+func writeOutputFile(config Config, stats Stats, render func(io.Writer, Config) error) (_, _ int64, err error) {
+	outputPath := config.OutputFile
+
+	// Create output file; closing a remote sink completes the upload
+	file, err := openOutput(outputPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if err != nil {
+			abortOutput(file, err)
+			return
+		}
+		err = file.Close()
+	}()
+
+	onDisk := &countingWriter{w: file}
+	var writer io.Writer = onDisk
+	config.separatorWidth = textSeparatorWidth(file, config)
+
+	// Add compression if requested
+	var compressor io.WriteCloser
+	if config.Compress {
+		compressor, err = newCompressor(onDisk, config, stats)
+		if err != nil {
+			return 0, 0, err
+		}
+		writer = compressor
+	}
+
+	rendered := &countingWriter{w: writer}
+	if err = render(rendered, config); err != nil {
+		return onDisk.n, rendered.n, err
+	}
+
+	// Close the compressor before reading the on-disk count so the
+	// trailer is included.
+	if compressor != nil {
+		if err := compressor.Close(); err != nil {
+			return onDisk.n, rendered.n, err
+		}
+	}
+	return onDisk.n, rendered.n, nil
+}
+
+func writeTextOutput(fileInfos []FileInfo, writer io.Writer, config Config, stats Stats) (int64, error) {
+	return writeTextStream(fileStream(fileInfos), writer, config, stats)
+}
+
+// writeTextStream writes the text document with one section per entry
+// received from files. The header is rendered from stats before the first
+// file arrives; the footer counts the files that actually did. files is
+// always drained, even on error, so its producer never blocks.
+func writeTextStream(files <-chan FileInfo, writer io.Writer, config Config, stats Stats) (int64, error) {
+	defer drainFiles(files)
+	totalBytes := int64(0)
+	bufWriter := bufio.NewWriter(writer)
+
+	data := newDocumentData(config, stats)
+	header := fmt.Sprintf("Pecel Output\n")
+	header += fmt.Sprintf("Generated: %s\n", data.Generated)
+	header += fmt.Sprintf("Files: %d | Directories: %d | Total Size: %s\n\n",
+		stats.FilesProcessed, stats.Directories, formatBytes(stats.TotalBytes))
+	header, err := documentText(config.headerTemplate, data, header)
+	if err != nil {
+		return 0, err
+	}
+	if config.NoHeader {
+		header = ""
+	}
+
+	tally := newStreamTally(stats, config)
+	footer := func(outputSize int64, omitted int) (string, error) {
+		if config.NoFooter {
+			return "", nil
+		}
+		final := tally.final()
+		footer := fmt.Sprintf("\n\n=== SUMMARY ===\n")
+		footer += fmt.Sprintf("Files processed: %d\n", final.FilesProcessed)
+		footer += fmt.Sprintf("Directories scanned: %d\n", final.Directories)
+		footer += fmt.Sprintf("Total input size: %s\n", formatBytes(final.TotalBytes))
+		footer += fmt.Sprintf("Content size: %s\n", formatBytes(outputSize))
+		footer += fmt.Sprintf("Processing time: %.2f seconds\n", final.Duration)
+		if omitted > 0 {
+			footer += fmt.Sprintf("Files omitted (-max-output-lines %d): %d\n", config.MaxOutputLines, omitted)
+		}
+		data.Stats, data.OutputSize, data.Omitted = final, outputSize, omitted
+		return documentText(config.footerTemplate, data, footer)
+	}
+	longestFooter, err := footer(0, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	n, _ := bufWriter.WriteString(header)
+	totalBytes += int64(n)
+
+	separatorWidth := config.separatorWidth
+	if separatorWidth <= 0 {
+		separatorWidth = defaultSeparatorWidth
+	}
+
+	lines, reserve := strings.Count(header, "\n"), strings.Count(longestFooter, "\n")
+	omitted := 0
+	for info := range files {
+		tally.add(info)
+		// Once one section is over -max-output-lines, the rest are counted
+		if omitted > 0 {
+			omitted++
+			continue
+		}
+		if isCompactSection(info, config) {
+			section := fmt.Sprintf("\n(empty) %s | Size: %s\n", textSectionTitle(info, config), formatBytes(info.Size))
+			if !fitsLineCap(section, &lines, reserve, config) {
+				omitted = 1
+				continue
+			}
+			n, _ := bufWriter.WriteString(section)
+			totalBytes += int64(n)
+			continue
+		}
 
-				// Update progress
-				curr := atomic.AddInt32(&processed, 1)
-				if verbose && !quiet && curr%10 == 0 {
-					fmt.Printf("%s Worker %d: Processed %d/%d files\n",
-						cyan("→"), workerID, curr, totalFiles)
-				} else if !verbose && !quiet && totalFiles > 10 && int(curr)%((totalFiles/10)+1) == 0 {
-					// Show overall progress for larger operations
-					progress := float64(curr) / float64(totalFiles) * 100
-					fmt.Printf("%s Overall progress: %d/%d files (%.1f%%)\n",
-						cyan("→"), curr, totalFiles, progress)
-				}
+		section := fmt.Sprintf("\n%s\n%s\n", strings.Repeat("=", separatorWidth), textSectionTitle(info, config))
+		section += fmt.Sprintf("Size: %s", formatBytes(info.Size))
+		if hasLineCount(info, config) {
+			section += fmt.Sprintf(" | Lines: %d", info.LineCount)
+		}
+		section += fmt.Sprintf(" | Modified: %s", displayModified(info, config))
+		if info.DiffStatus != "" {
+			section += fmt.Sprintf(" | Status: %s", info.DiffStatus)
+		}
+		if info.LinkTarget != "" {
+			section += fmt.Sprintf(" | Symlink: %s", info.LinkTarget)
+		}
+		if author := gitAuthorLabel(info); author != "" {
+			section += fmt.Sprintf(" | Last commit: %s", author)
+		}
+		if info.ContentOmitted {
+			section += " | Content omitted"
+		}
+		section += "\n"
+		section += fmt.Sprintf("%s\n", strings.Repeat("-", separatorWidth))
+		section += info.Content + "\n"
+		section += fmt.Sprintf("%s\n", strings.Repeat("=", separatorWidth))
+
+		if !fitsLineCap(section, &lines, reserve, config) {
+			omitted = 1
+			continue
+		}
+		n, _ := bufWriter.WriteString(section)
+		totalBytes += int64(n)
+	}
+
+	footerText, err := footer(totalBytes, omitted)
+	if err != nil {
+		return totalBytes, err
+	}
+	n, _ = bufWriter.WriteString(footerText)
+	totalBytes += int64(n)
+
+	bufWriter.Flush()
+	return totalBytes, nil
+}
+
+// numberFiles records each file's 1-based position in the final order,
+// and the total, for -number-files.
+func numberFiles(fileInfos []FileInfo, config *Config) {
+	for i := range fileInfos {
+		fileInfos[i].Index = i + 1
+	}
+	config.totalFiles = len(fileInfos)
+}
+
+// fileLabel returns "File N of M" for a file numbered by -number-files, or
+// "" when files are not numbered.
+func fileLabel(info FileInfo, config Config) string {
+	if info.Index == 0 {
+		return ""
+	}
+	return fmt.Sprintf("File %d of %d", info.Index, config.totalFiles)
+}
+
+// textSectionTitle is the path line of a text section, prefixed with the
+// file's label when files are numbered.
+func textSectionTitle(info FileInfo, config Config) string {
+	if label := fileLabel(info, config); label != "" {
+		return label + ": " + info.RelativePath
+	}
+	return info.RelativePath
+}
+
+// markdownFileLabel is the "File N" heading prefix of the i-th markdown
+// section; numbered files use their bundle-wide label instead.
+func markdownFileLabel(info FileInfo, i int, config Config) string {
+	if label := fileLabel(info, config); label != "" {
+		return label
+	}
+	return fmt.Sprintf("File %d", i+1)
+}
+
+// isCompactSection reports whether -compact-empty-sections collapses info:
+// a file whose content is empty or only whitespace, either on disk or after
+// the transforms. Symlink and metadata-only entries keep their sections.
+func isCompactSection(info FileInfo, config Config) bool {
+	return config.CompactEmptySections && !info.ContentOmitted && info.LinkTarget == "" &&
+		strings.TrimSpace(info.Content) == ""
+}
+
+// fitsLineCap reports whether section still fits under -max-output-lines,
+// keeping reserve lines free for the footer, and adds it to used if so.
+func fitsLineCap(section string, used *int, reserve int, config Config) bool {
+	if config.MaxOutputLines <= 0 {
+		return true
+	}
+	n := strings.Count(section, "\n")
+	if *used+n+reserve > config.MaxOutputLines {
+		return false
+	}
+	*used += n
+	return true
+}
+
+// parallelMarshalThreshold is the number of entries below which JSON
+// marshaling stays on the writing goroutine; for smaller runs the worker
+// coordination costs more than it saves.
+const parallelMarshalThreshold = 512
+
+// parseJSONPrettyThreshold parses a -json-pretty-threshold value: a byte
+// count, or a file count with a "files" suffix.
+func parseJSONPrettyThreshold(value string) (maxBytes int64, maxFiles int, err error) {
+	value = strings.TrimSpace(value)
+	if count, ok := strings.CutSuffix(value, "files"); ok {
+		maxFiles, err = strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || maxFiles < 1 {
+			return 0, 0, fmt.Errorf("%q: want a positive file count like 200files", value)
+		}
+		return 0, maxFiles, nil
+	}
+	maxBytes, err = strconv.ParseInt(value, 10, 64)
+	if err != nil || maxBytes < 1 {
+		return 0, 0, fmt.Errorf("%q: want a positive byte count or a file count like 200files", value)
+	}
+	return maxBytes, 0, nil
+}
+
+// jsonPretty reports whether JSON output should be indented: always, unless
+// the run is over -json-pretty-threshold. Content bytes stand in for the
+// output size, which is not known until it has been written.
+func jsonPretty(config Config, stats Stats) bool {
+	if config.jsonPrettyMaxFiles > 0 && stats.FilesProcessed > config.jsonPrettyMaxFiles {
+		return false
+	}
+	if config.jsonPrettyMaxBytes > 0 && stats.TotalBytes > config.jsonPrettyMaxBytes {
+		return false
+	}
+	return true
+}
+
+// marshalJSONEntry renders one entry of the files array, indented for its
+// place in the document or compact.
+func marshalJSONEntry(info FileInfo, pretty bool) ([]byte, error) {
+	if pretty {
+		return json.MarshalIndent(info, "    ", "  ")
+	}
+	return json.Marshal(info)
+}
+
+// writeJSONOutput streams the document entry by entry instead of encoding one
+// big map, so the files array never has to be rendered in a single buffer.
+// The layout matches what json.Encoder with a two-space indent produces, or
+// json.Marshal once the run is over -json-pretty-threshold.
+func writeJSONOutput(fileInfos []FileInfo, writer io.Writer, config Config, stats Stats) (int64, error) {
+	return writeJSONStream(fileStream(fileInfos), writer, config, stats)
+}
+
+// writeJSONStream writes the JSON document with one entry per file received
+// from files. The metadata follows the files array, so it counts the files
+// that actually arrived. files is always drained, even on error.
+func writeJSONStream(files <-chan FileInfo, writer io.Writer, config Config, stats Stats) (int64, error) {
+	defer drainFiles(files)
+	counter := &countingWriter{w: writer}
+	bufWriter := bufio.NewWriter(counter)
+	pretty := jsonPretty(config, stats)
+	generated := time.Now().Format(time.RFC3339)
+	tally := newStreamTally(stats, config)
+
+	if pretty {
+		bufWriter.WriteString("{\n  \"files\": [")
+	} else {
+		bufWriter.WriteString(`{"files":[`)
+	}
+	first := true
+	emit := func(info FileInfo, chunk []byte) error {
+		tally.add(info)
+		if !first {
+			bufWriter.WriteString(",")
+		}
+		first = false
+		if pretty {
+			bufWriter.WriteString("\n    ")
+		}
+		_, err := bufWriter.Write(chunk)
+		return err
+	}
+
+	var err error
+	if config.MarshalWorkers > 1 && stats.FilesProcessed >= parallelMarshalThreshold {
+		err = marshalEntriesParallel(files, config.MarshalWorkers, pretty, emit)
+	} else {
+		for info := range files {
+			chunk, merr := marshalJSONEntry(info, pretty)
+			if merr != nil {
+				return counter.n, merr
 			}
-		}(i)
+			if err = emit(info, chunk); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return counter.n, err
 	}
 
-	// Send files to workers
-	for _, path := range paths {
-		fileChan <- path
+	metadata := documentMetadata(tally.final(), stats, config, generated)
+
+	var meta []byte
+	if pretty {
+		if !first {
+			bufWriter.WriteString("\n  ")
+		}
+		bufWriter.WriteString("],\n  \"metadata\": ")
+		meta, err = json.MarshalIndent(metadata, "  ", "  ")
+	} else {
+		bufWriter.WriteString(`],"metadata":`)
+		meta, err = json.Marshal(metadata)
 	}
-	close(fileChan)
+	if err != nil {
+		return counter.n, err
+	}
+	bufWriter.Write(meta)
+	if pretty {
+		bufWriter.WriteString("\n")
+	}
+	bufWriter.WriteString("}\n")
 
-	// Wait for workers to finish
+	if err := bufWriter.Flush(); err != nil {
+		return counter.n, err
+	}
+	return counter.n, nil
+}
+
+// documentMetadata is the metadata object of the JSON and YAML documents.
+// final holds the counts tallied while writing; stats the run's other
+// results.
+func documentMetadata(final, stats Stats, config Config, generated string) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"generated":     generated,
+		"version":       version,
+		"files_count":   final.FilesProcessed,
+		"directories":   final.Directories,
+		"total_size":    final.TotalBytes,
+		"duration_secs": final.Duration,
+	}
+	if stats.Diff != nil {
+		metadata["diff"] = stats.Diff
+	}
+	if config.LineCounts {
+		metadata["total_lines"] = final.TotalLines
+	}
+	if len(final.Groups) > 0 {
+		metadata["groups"] = final.Groups
+	}
+	if len(stats.Oversize) > 0 {
+		metadata["oversize"] = stats.Oversize
+	}
+	return metadata
+}
+
+// marshalEntriesParallel renders the entries received from files on a pool of
+// workers and hands the pre-rendered chunks to emit in their original order.
+// Each entry gets its own result slot; the slots queue is bounded so
+// marshaling can only run a fixed distance ahead of the writer.
+func marshalEntriesParallel(files <-chan FileInfo, workers int, pretty bool, emit func(FileInfo, []byte) error) error {
+	type result struct {
+		info FileInfo
+		data []byte
+		err  error
+	}
+	type job struct {
+		info FileInfo
+		slot chan result
+	}
+
+	jobs := make(chan job, workers)
+	slots := make(chan chan result, workers*4)
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				data, err := marshalJSONEntry(j.info, pretty)
+				j.slot <- result{info: j.info, data: data, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(slots)
+		defer close(jobs)
+		for info := range files {
+			slot := make(chan result, 1)
+			select {
+			case slots <- slot:
+			case <-stop:
+				return
+			}
+			jobs <- job{info: info, slot: slot}
+		}
+	}()
+
+	var err error
+	for slot := range slots {
+		r := <-slot
+		if r.err != nil {
+			err = r.err
+			break
+		}
+		if err = emit(r.info, r.data); err != nil {
+			break
+		}
+	}
+	if err != nil {
+		close(stop)
+		// Drain so the dispatcher and workers can exit.
+		for slot := range slots {
+			<-slot
+		}
+	}
 	wg.Wait()
-	close(resultChan)
-	close(errorChan)
+	return err
+}
 
-	// Collect results
-	var fileInfos []FileInfo
-	for info := range resultChan {
-		fileInfos = append(fileInfos, info)
-		stats.FilesProcessed++
-		stats.TotalBytes += info.Size
+// xmlMetadata is the <metadata> element of the XML output.
+type xmlMetadata struct {
+	Files       int          `xml:"files"`
+	Directories int          `xml:"directories"`
+	TotalSize   int64        `xml:"total_size"`
+	Duration    float64      `xml:"duration_seconds"`
+	Diff        *DiffSummary `xml:"diff,omitempty"`
+}
+
+func writeXMLOutput(fileInfos []FileInfo, writer io.Writer, config Config, stats Stats) (int64, error) {
+	return writeXMLStream(fileStream(fileInfos), writer, config, stats)
+}
+
+// writeXMLStream writes the XML document token by token: the root element,
+// one <file> element per entry received from files, then the metadata, which
+// counts the files actually written rather than the walk's estimate. Only the
+// entry being encoded is held in memory. files is always drained, even on
+// error, so its producer never blocks.
+func writeXMLStream(files <-chan FileInfo, writer io.Writer, config Config, stats Stats) (int64, error) {
+	defer drainFiles(files)
+
+	counter := &countingWriter{w: writer}
+	if _, err := io.WriteString(counter, xml.Header); err != nil {
+		return counter.n, err
+	}
+
+	encoder := xml.NewEncoder(counter)
+	encoder.Indent("", "  ")
+
+	root := xml.StartElement{
+		Name: xml.Name{Local: "filecombiner_output"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "version"}, Value: version},
+			{Name: xml.Name{Local: "generated"}, Value: time.Now().Format(time.RFC3339)},
+		},
+	}
+	if err := encoder.EncodeToken(root); err != nil {
+		return counter.n, err
+	}
+
+	tally := newStreamTally(stats, config)
+	fileElement := xml.StartElement{Name: xml.Name{Local: "file"}}
+	for info := range files {
+		tally.add(info)
+		if err := encoder.EncodeElement(info, fileElement); err != nil {
+			return counter.n, err
+		}
+	}
+
+	final := tally.final()
+	metadata := xmlMetadata{
+		Files:       final.FilesProcessed,
+		Directories: final.Directories,
+		TotalSize:   final.TotalBytes,
+		Duration:    final.Duration,
+		Diff:        stats.Diff,
+	}
+	if err := encoder.EncodeElement(metadata, xml.StartElement{Name: xml.Name{Local: "metadata"}}); err != nil {
+		return counter.n, err
+	}
+
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return counter.n, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return counter.n, err
+	}
+	return counter.n, nil
+}
+
+// fileStream feeds an in-memory slice to a writer that consumes a channel.
+func fileStream(fileInfos []FileInfo) <-chan FileInfo {
+	files := make(chan FileInfo)
+	go func() {
+		defer close(files)
+		for _, info := range fileInfos {
+			files <- info
+		}
+	}()
+	return files
+}
+
+// drainFiles receives what is left in files, so a writer that stops early
+// does not leave its producer blocked.
+func drainFiles(files <-chan FileInfo) {
+	for range files {
+	}
+}
+
+// streamTally counts the files a stream writer receives. Parts of a document
+// written after the files (footers, JSON metadata) report these counts, since
+// a streamed run only has the walk's estimate when the header goes out.
+type streamTally struct {
+	stats  Stats
+	config Config
+	start  time.Time
+}
+
+func newStreamTally(stats Stats, config Config) *streamTally {
+	stats.FilesProcessed, stats.TotalBytes, stats.TotalLines = 0, 0, 0
+	if config.GroupSummary {
+		stats.Groups = nil
+	}
+	return &streamTally{stats: stats, config: config, start: time.Now()}
+}
+
+func (t *streamTally) add(info FileInfo) {
+	t.stats.FilesProcessed++
+	t.stats.TotalBytes += info.Size
+	t.stats.TotalLines += info.LineCount
+	if t.config.GroupSummary {
+		t.stats.Groups = addToGroups(t.stats.Groups, info, t.config)
+	}
+}
+
+// final returns the counted stats, with the time spent writing added to the
+// duration.
+func (t *streamTally) final() Stats {
+	stats := t.stats
+	stats.Duration += time.Since(t.start).Seconds()
+	sortGroups(stats.Groups)
+	return stats
+}
+
+func writeMarkdownOutput(fileInfos []FileInfo, writer io.Writer, config Config, stats Stats) (int64, error) {
+	toc := ""
+	if config.MarkdownTOC {
+		toc = markdownTOC(fileInfos, config)
+	}
+	return writeMarkdownDocument(fileStream(fileInfos), writer, config, stats, toc)
+}
+
+// writeMarkdownStream is writeTextStream for the markdown document.
+func writeMarkdownStream(files <-chan FileInfo, writer io.Writer, config Config, stats Stats) (int64, error) {
+	return writeMarkdownDocument(files, writer, config, stats, "")
+}
+
+// writeMarkdownDocument writes the markdown document with toc, the table of
+// contents, between the header and the first file.
+func writeMarkdownDocument(files <-chan FileInfo, writer io.Writer, config Config, stats Stats, toc string) (int64, error) {
+	defer drainFiles(files)
+	totalBytes := int64(0)
+	bufWriter := bufio.NewWriter(writer)
+
+	data := newDocumentData(config, stats)
+	header := fmt.Sprintf("# Pecel Output\n\n")
+	header += fmt.Sprintf("**Generated**: %s  \n", data.Generated)
+	header += fmt.Sprintf("**Files**: %d | **Directories**: %d | **Total Size**: %s  \n\n",
+		stats.FilesProcessed, stats.Directories, formatBytes(stats.TotalBytes))
+	header, err := documentText(config.headerTemplate, data, header)
+	if err != nil {
+		return 0, err
+	}
+	if config.NoHeader {
+		header = ""
+	}
+	header += toc
+
+	tally := newStreamTally(stats, config)
+	footer := func(omitted int) (string, error) {
+		if config.NoFooter {
+			return "", nil
+		}
+		final := tally.final()
+		footer := fmt.Sprintf("## Summary\n\n")
+		footer += fmt.Sprintf("- **Files processed**: %d\n", final.FilesProcessed)
+		footer += fmt.Sprintf("- **Directories scanned**: %d\n", final.Directories)
+		footer += fmt.Sprintf("- **Total input size**: %s\n", formatBytes(final.TotalBytes))
+		footer += fmt.Sprintf("- **Processing time**: %.2f seconds\n", final.Duration)
+		if omitted > 0 {
+			footer += fmt.Sprintf("- **Files omitted** (`-max-output-lines %d`): %d\n", config.MaxOutputLines, omitted)
+		}
+		data.Stats, data.Omitted = final, omitted
+		return documentText(config.footerTemplate, data, footer)
+	}
+	longestFooter, err := footer(1)
+	if err != nil {
+		return 0, err
+	}
+
+	n, _ := bufWriter.WriteString(header)
+	totalBytes += int64(n)
+
+	lines, reserve := strings.Count(header, "\n"), strings.Count(longestFooter, "\n")
+	omitted, i := 0, -1
+	for info := range files {
+		tally.add(info)
+		i++
+		if omitted > 0 {
+			omitted++
+			continue
+		}
+		if isCompactSection(info, config) {
+			section := fmt.Sprintf("*%s: `%s` is empty (%s)*\n\n", markdownFileLabel(info, i, config), info.RelativePath, formatBytes(info.Size))
+			if !fitsLineCap(section, &lines, reserve, config) {
+				omitted = 1
+				continue
+			}
+			n, _ := bufWriter.WriteString(section)
+			totalBytes += int64(n)
+			continue
+		}
+
+		section := fmt.Sprintf("## %s: `%s`\n\n", markdownFileLabel(info, i, config), info.RelativePath)
+		section += fmt.Sprintf("**Size**: %s  \n", formatBytes(info.Size))
+		if hasLineCount(info, config) {
+			section += fmt.Sprintf("**Lines**: %d  \n", info.LineCount)
+		}
+		section += fmt.Sprintf("**Modified**: %s  \n", displayModified(info, config))
+		if info.DiffStatus != "" {
+			section += fmt.Sprintf("**Status**: %s  \n", info.DiffStatus)
+		}
+		if info.LinkTarget != "" {
+			section += fmt.Sprintf("**Symlink to**: `%s`  \n", info.LinkTarget)
+		}
+		if author := gitAuthorLabel(info); author != "" {
+			section += fmt.Sprintf("**Last commit**: %s  \n", author)
+		}
+		section += "\n"
+		if info.ContentOmitted {
+			section += "*Content omitted (below -content-max-depth)*\n\n"
+		} else {
+			section += "### Content\n"
+			section += markdownCodeBlocks(info.Content, markdownFenceLanguage(info, config), config.MarkdownBlockLines)
+		}
+		section += "---\n\n"
+
+		if !fitsLineCap(section, &lines, reserve, config) {
+			omitted = 1
+			continue
+		}
+		n, _ := bufWriter.WriteString(section)
+		totalBytes += int64(n)
+	}
+
+	data.OutputSize = totalBytes
+	footerText, err := footer(omitted)
+	if err != nil {
+		return totalBytes, err
+	}
+	n, _ = bufWriter.WriteString(footerText)
+	totalBytes += int64(n)
+
+	bufWriter.Flush()
+	return totalBytes, nil
+}
+
+// summaryRow is one "label: value" line of the processing summary.
+type summaryRow struct {
+	label string
+	value string
+}
+
+// maxSummaryWidth is the widest the summary box is drawn, even on wide terminals.
+const maxSummaryWidth = 50
+
+// markdownCodeBlocks fences content as one code block, or with maxLines set,
+// as consecutive blocks of at most maxLines lines joined by "(continued)"
+// markers, since browsers render single huge code blocks slowly.
+func markdownCodeBlocks(content, lang string, maxLines int) string {
+	lines := strings.Split(content, "\n")
+	if maxLines <= 0 || len(lines) <= maxLines {
+		return "```" + lang + "\n" + content + "\n```\n\n"
+	}
+
+	var b strings.Builder
+	for start := 0; start < len(lines); start += maxLines {
+		end := start + maxLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if start > 0 {
+			fmt.Fprintf(&b, "*(continued from line %d)*\n\n", start+1)
+		}
+		b.WriteString("```" + lang + "\n")
+		b.WriteString(strings.Join(lines[start:end], "\n"))
+		b.WriteString("\n```\n\n")
+	}
+	return b.String()
+}
+
+// markdownFenceLanguage picks the language tag for a file's code fence: by
+// extension (-lang-map, then the built-in table), else inferred from content
+// when content types were detected (-detect-type). Encoded binaries and
+// unknown files get a bare fence.
+func markdownFenceLanguage(info FileInfo, config Config) string {
+	if info.Encoding != "" {
+		return ""
+	}
+	if lang, ok := extensionLanguage(info.RelativePath, config.langMap); ok {
+		return lang
+	}
+	if info.ContentType == "" {
+		return ""
+	}
+	return contentLanguage(info.Content, info.ContentType)
+}
+
+func printSummary(stats Stats, config Config) {
+	rows := []summaryRow{
+		{"Files processed", green(strconv.Itoa(stats.FilesProcessed))},
+		{"Directories scanned", green(strconv.Itoa(stats.Directories))},
+		{"Total size", green(formatBytes(stats.TotalBytes))},
+	}
+	if config.LineCounts {
+		rows = append(rows, summaryRow{"Total lines", green(strconv.Itoa(stats.TotalLines))})
+	}
+	rows = append(rows, []summaryRow{
+		{"Processing time", fmt.Sprintf("%.2f seconds", stats.Duration)},
+		{"  Walk", fmt.Sprintf("%.2f seconds", stats.WalkDuration)},
+		{"  Read/process", fmt.Sprintf("%.2f seconds", stats.ProcessDuration)},
+	}...)
+	if !config.DryRun && config.jsonStream == nil {
+		rows = append(rows, summaryRow{"  Write", fmt.Sprintf("%.2f seconds", stats.WriteDuration)})
+	}
+	if stats.SpecialSkipped > 0 {
+		rows = append(rows, summaryRow{"Special skipped", yellow(strconv.Itoa(stats.SpecialSkipped))})
+	}
+	if stats.Vanished > 0 {
+		rows = append(rows, summaryRow{"Vanished", cyan(strconv.Itoa(stats.Vanished))})
+	}
+	if stats.PathTooLong > 0 {
+		rows = append(rows, summaryRow{"Path too long", yellow(strconv.Itoa(stats.PathTooLong))})
+	}
+	if stats.DroppedByName > 0 {
+		rows = append(rows, summaryRow{"Same-name dropped", cyan(strconv.Itoa(stats.DroppedByName))})
+	}
+	if n := len(stats.LargeBinarySkipped); n > 0 {
+		rows = append(rows, summaryRow{"Large binaries skipped", red(strconv.Itoa(n))})
+	}
+	if stats.BinariesSkipped > 0 {
+		rows = append(rows, summaryRow{"Binaries skipped", cyan(strconv.Itoa(stats.BinariesSkipped))})
+	}
+	if groups := stats.Duplicates; len(groups) > 0 {
+		var wasted int64
+		for _, group := range groups {
+			wasted += group.Wasted()
+		}
+		rows = append(rows, summaryRow{"Duplicates", fmt.Sprintf("%s groups, %s wasted",
+			yellow(strconv.Itoa(len(groups))), yellow(formatBytes(wasted)))})
+	}
+	if d := stats.Diff; d != nil {
+		rows = append(rows, summaryRow{"Changes", fmt.Sprintf("%s added, %s modified, %d unchanged, %s removed",
+			green(strconv.Itoa(d.Added)), yellow(strconv.Itoa(d.Modified)), d.Unchanged, red(strconv.Itoa(len(d.Removed))))})
+	}
+
+	if !config.DryRun {
+		if config.OutputDirMirror != "" {
+			rows = append(rows, summaryRow{"Mirrored to", green(config.OutputDirMirror)})
+		} else {
+			rows = append(rows, summaryRow{"Output format", green(config.OutputFormat)})
+		}
+		if config.Compress {
+			rows = append(rows,
+				summaryRow{"Compression", green(config.Compression)},
+				summaryRow{"Uncompressed size", green(formatBytes(stats.UncompressedSize))})
+		}
+		rows = append(rows, summaryRow{"Output size", green(formatBytes(stats.OutputSize))})
+		if config.ExternalizeContent != "" {
+			rows = append(rows, summaryRow{"Content blobs", fmt.Sprintf("%s in %s", green(strconv.Itoa(stats.ContentBlobs)), config.ExternalizeContent)})
+		}
+		if len(stats.OutputParts) > 0 {
+			rows = append(rows, summaryRow{"Output parts", green(strconv.Itoa(len(stats.OutputParts)))})
+			if stats.ResumedParts > 0 {
+				rows = append(rows, summaryRow{"Resumed parts", green(strconv.Itoa(stats.ResumedParts))})
+			}
+			for i, part := range stats.OutputParts {
+				rows = append(rows, summaryRow{fmt.Sprintf("  Part %d", i+1), part})
+			}
+		}
+		// Without a codec the output only differs from the input by the
+		// format's framing, so there is no ratio worth showing
+		if config.Compress && stats.UncompressedSize > 0 {
+			ratio := float64(stats.OutputSize) / float64(stats.UncompressedSize) * 100
+			rows = append(rows, summaryRow{"Compression ratio", fmt.Sprintf("%.1f%%", ratio)})
+		}
 	}
 
-	// Report errors
-	if !quiet {
-		for err := range errorChan {
-			fmt.Printf("%s %v\n", red("✗"), err)
+	if config.PlainSummary {
+		fmt.Println()
+		fmt.Println("Processing Summary")
+		for _, row := range rows {
+			fmt.Printf("%s: %s\n", row.label, row.value)
 		}
+		return
 	}
 
-	return fileInfos
+	width := summaryWidth()
+	fmt.Printf("\n%s %s\n", cyan("┌"), strings.Repeat("─", width))
+	fmt.Printf("%s Processing Summary\n", cyan("│"))
+	fmt.Printf("%s %s\n", cyan("├"), strings.Repeat("─", width))
+	for _, row := range rows {
+		fmt.Printf("%s %-21s%s\n", cyan("│"), row.label+":", row.value)
+	}
+	fmt.Printf("%s %s\n", cyan("└"), strings.Repeat("─", width))
 }
 
-func processSingleFile(path, baseDir string) (FileInfo, error) {
-	info := FileInfo{
-		Path:         path,
-		RelativePath: getRelativePath(path, baseDir),
-	}
+// defaultSeparatorWidth is the length of text output separators when the
+// output is not a terminal and -separator-width is unset.
+const defaultSeparatorWidth = 80
 
-	// Get file stats
-	fileInfo, err := os.Stat(path)
-	if err != nil {
-		return info, err
+// textSeparatorWidth returns -separator-width, or the terminal's width when
+// the uncompressed output goes straight to one.
+func textSeparatorWidth(output io.Writer, config Config) int {
+	if config.SeparatorWidth > 0 {
+		return config.SeparatorWidth
 	}
+	if f, ok := output.(*os.File); ok && !config.Compress && term.IsTerminal(int(f.Fd())) {
+		if cols, _, err := term.GetSize(int(f.Fd())); err == nil && cols > 0 {
+			return cols
+		}
+	}
+	return defaultSeparatorWidth
+}
 
-	info.Size = fileInfo.Size()
-	info.Modified = fileInfo.ModTime().Format("2006-01-02 15:04:05")
-
-	// Read file content
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return info, err
+// summaryWidth fits the summary rules to the terminal, falling back to the
+// full width when stdout is not a terminal.
+func summaryWidth() int {
+	cols, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || cols <= 0 {
+		return maxSummaryWidth
+	}
+	// Leave room for the corner glyph and the space after it.
+	width := cols - 2
+	if width > maxSummaryWidth {
+		width = maxSummaryWidth
 	}
+	if width < 10 {
+		width = 10
+	}
+	return width
+}
 
-	info.Content = string(content)
-	return info, nil
+// defaultConfig holds the settings a configuration file starts from, matching
+// the flag defaults, so a file only needs the keys it changes.
+func defaultConfig() Config {
+	return Config{
+		InputDir:      ".",
+		OutputFile:    "combined.txt",
+		ExcludeHidden: true,
+		OutputFormat:  "text",
+	}
 }
 
-func writeOutput(fileInfos []FileInfo, outputPath, format string, compress bool, stats Stats) (int64, error) {
-	var writer io.Writer
+// loadConfig reads a JSON configuration file, or a YAML one when the name
+// ends in .yaml or .yml. YAML uses the same keys as JSON.
+func loadConfig(filename string) (Config, error) {
+	config := defaultConfig()
+	err := readConfigFile(filename, &config)
+	return config, err
+}
 
-	// Create output file
-	file, err := os.Create(outputPath)
+// readConfigFile decodes filename into config, leaving the fields the file
+// does not mention as they are.
+func readConfigFile(filename string, config *Config) error {
+	data, err := os.ReadFile(filename)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	defer file.Close()
 
-	writer = file
-
-	// Add compression if requested
-	if compress {
-		gzWriter := gzip.NewWriter(file)
-		defer gzWriter.Close()
-		writer = gzWriter
-		outputPath += ".gz"
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		// Round-trip through JSON so the json tags define the keys
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return err
+		}
 	}
 
-	// Write based on format
-	switch strings.ToLower(format) {
-	case "json":
-		return writeJSONOutput(fileInfos, writer, stats)
-	case "xml":
-		return writeXMLOutput(fileInfos, writer, stats)
-	case "markdown", "md":
-		return writeMarkdownOutput(fileInfos, writer, stats)
-	default: // text
-		return writeTextOutput(fileInfos, writer, stats)
-	}
+	return json.Unmarshal(data, config)
 }
 
-func writeTextOutput(fileInfos []FileInfo, writer io.Writer, stats Stats) (int64, error) {
-	totalBytes := int64(0)
-	bufWriter := bufio.NewWriter(writer)
-
-	header := fmt.Sprintf("Pecel Output\n")
-	header += fmt.Sprintf("Generated: %s\n", time.Now().Format("2006-01-02 15:04:05"))
-	header += fmt.Sprintf("Files: %d | Directories: %d | Total Size: %s\n\n",
-		stats.FilesProcessed, stats.Directories, formatBytes(stats.TotalBytes))
+// configList collects the -config flag, which may be given more than once.
+type configList []string
 
-	n, _ := bufWriter.WriteString(header)
-	totalBytes += int64(n)
+func (l *configList) String() string { return strings.Join(*l, ",") }
 
-	for _, info := range fileInfos {
-		section := fmt.Sprintf("\n%s\n%s\n", strings.Repeat("=", 80), info.RelativePath)
-		section += fmt.Sprintf("Size: %s | Modified: %s\n", formatBytes(info.Size), info.Modified)
-		section += fmt.Sprintf("%s\n", strings.Repeat("-", 80))
-		section += info.Content + "\n"
-		section += fmt.Sprintf("%s\n", strings.Repeat("=", 80))
+func (l *configList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
 
-		n, _ := bufWriter.WriteString(section)
-		totalBytes += int64(n)
+// loadConfigs reads each file in turn over the defaults and the files before
+// it, so later files override earlier ones. Every key a file gives applies,
+// including false, 0, "" and empty lists, so a later file can also switch off
+// or clear a setting. Profiles are combined by name, a later file's profile
+// replacing an earlier one of the same name.
+func loadConfigs(filenames []string) (Config, error) {
+	var expanded []string
+	for _, filename := range filenames {
+		files, err := configIncludes(filename, nil)
+		if err != nil {
+			return Config{}, err
+		}
+		expanded = append(expanded, files...)
 	}
 
-	footer := fmt.Sprintf("\n\n=== SUMMARY ===\n")
-	footer += fmt.Sprintf("Files processed: %d\n", stats.FilesProcessed)
-	footer += fmt.Sprintf("Directories scanned: %d\n", stats.Directories)
-	footer += fmt.Sprintf("Total input size: %s\n", formatBytes(stats.TotalBytes))
-	footer += fmt.Sprintf("Output size: %s\n", formatBytes(totalBytes))
-	footer += fmt.Sprintf("Processing time: %.2f seconds\n", stats.Duration)
+	config := defaultConfig()
+	for _, filename := range expanded {
+		if err := readConfigFile(filename, &config); err != nil {
+			return config, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	config.Include = nil
+	return config, nil
+}
 
-	n, _ = bufWriter.WriteString(footer)
-	totalBytes += int64(n)
+// configIncludes returns the files to load for filename, in merge order:
+// the files it includes, each expanded the same way, then filename itself.
+// chain holds the absolute paths of the files including this one; meeting
+// one of them again is an include cycle and an error, rather than a loop.
+func configIncludes(filename string, chain []string) ([]string, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	for i, including := range chain {
+		if including == abs {
+			cycle := append(chain[i:len(chain):len(chain)], abs)
+			return nil, fmt.Errorf("configuration include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	chain = append(chain[:len(chain):len(chain)], abs)
 
-	bufWriter.Flush()
-	return totalBytes, nil
+	var layer Config
+	if err := readConfigFile(filename, &layer); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	var files []string
+	for _, include := range layer.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(filename), include)
+		}
+		included, err := configIncludes(include, chain)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, included...)
+	}
+	return append(files, filename), nil
 }
 
-func writeJSONOutput(fileInfos []FileInfo, writer io.Writer, stats Stats) (int64, error) {
-	output := map[string]interface{}{
-		"metadata": map[string]interface{}{
-			"generated":     time.Now().Format(time.RFC3339),
-			"version":       version,
-			"files_count":   stats.FilesProcessed,
-			"directories":   stats.Directories,
-			"total_size":    stats.TotalBytes,
-			"duration_secs": stats.Duration,
-		},
-		"files": fileInfos,
-	}
+// configFileNames are the per-project configuration files discovered when no
+// -config is given, in order of preference within a directory.
+var configFileNames = []string{".pecel.json", ".pecel.yaml", ".pecel.yml"}
 
-	encoder := json.NewEncoder(writer)
-	encoder.SetIndent("", "  ")
-	err := encoder.Encode(output)
+// discoverConfig looks for a project configuration file in the current
+// directory and each parent up to the home directory (or the filesystem root
+// outside it), returning the nearest one or "" if there is none.
+func discoverConfig() string {
+	dir, err := os.Getwd()
 	if err != nil {
-		return 0, err
+		return ""
+	}
+	home, _ := os.UserHomeDir()
+	for {
+		for _, name := range configFileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if dir == home || parent == dir {
+			return ""
+		}
+		dir = parent
 	}
+}
 
-	// Estimate size (not exact but good enough)
-	data, _ := json.Marshal(output)
-	return int64(len(data)), nil
+// touchOutput sets the modification and access time of the written output.
+// value is "newest" (the most recent mtime among the bundled files) or any
+// timestamp accepted by parseTimestamp.
+func touchOutput(path, value string, fileInfos []FileInfo) error {
+	var mtime time.Time
+	if value == "newest" {
+		for _, info := range fileInfos {
+			if info.modTime.After(mtime) {
+				mtime = info.modTime
+			}
+		}
+		if mtime.IsZero() {
+			// Nothing was bundled; leave the fresh mtime in place.
+			return nil
+		}
+	} else {
+		t, err := parseTimestamp(value)
+		if err != nil {
+			return err
+		}
+		mtime = t
+	}
+	return os.Chtimes(path, mtime, mtime)
 }
 
-func writeXMLOutput(fileInfos []FileInfo, writer io.Writer, stats Stats) (int64, error) {
-	type XMLOutput struct {
-		XMLName   xml.Name `xml:"filecombiner_output"`
-		Version   string   `xml:"version,attr"`
-		Generated string   `xml:"generated,attr"`
-		Metadata  struct {
-			Files       int     `xml:"files"`
-			Directories int     `xml:"directories"`
-			TotalSize   int64   `xml:"total_size"`
-			Duration    float64 `xml:"duration_seconds"`
-		} `xml:"metadata"`
-		Files []FileInfo `xml:"file"`
+// parseTimestamp accepts an RFC 3339 timestamp, a "2006-01-02 15:04:05" local
+// time, a plain date, or Unix seconds.
+func parseTimestamp(value string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
 	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q (use RFC 3339, \"2006-01-02 15:04:05\", a date, or Unix seconds)", value)
+}
 
-	output := XMLOutput{
-		Version:   version,
-		Generated: time.Now().Format(time.RFC3339),
+// applyProfile decodes the named profile from cfg over the rest of cfg.
+func applyProfile(cfg Config, name string) (Config, error) {
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(cfg.Profiles))
+		for n := range cfg.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return cfg, fmt.Errorf("profile %q not found: configuration defines no profiles", name)
+		}
+		return cfg, fmt.Errorf("profile %q not found (available: %s)", name, strings.Join(names, ", "))
 	}
-	output.Metadata.Files = stats.FilesProcessed
-	output.Metadata.Directories = stats.Directories
-	output.Metadata.TotalSize = stats.TotalBytes
-	output.Metadata.Duration = stats.Duration
-	output.Files = fileInfos
+	// Every key the profile gives wins, so it can also switch settings off
+	profiles := cfg.Profiles
+	cfg.Profiles = nil
+	if err := json.Unmarshal(profile, &cfg); err != nil {
+		return cfg, fmt.Errorf("profile %q: %w", name, err)
+	}
+	cfg.Profiles = profiles
+	cfg.Include = nil
+	return cfg, nil
+}
 
-	encoder := xml.NewEncoder(writer)
-	encoder.Indent("", "  ")
+// labelPath prefixes a relative path with -root-label, so bundles shared out
+// of context still name the project each file belongs to.
+func labelPath(relPath string, config Config) string {
+	if config.RootLabel == "" {
+		return relPath
+	}
+	return filepath.Join(config.RootLabel, relPath)
+}
 
-	// Write XML header
-	writer.Write([]byte(xml.Header))
+// pathDepth is how many levels below the input directory a relative path
+// lies; files directly in it are at depth 1.
+func pathDepth(relPath string) int {
+	return strings.Count(filepath.ToSlash(filepath.Clean(relPath)), "/") + 1
+}
 
-	err := encoder.Encode(output)
+func getRelativePath(path, baseDir string) string {
+	relPath, err := filepath.Rel(baseDir, path)
 	if err != nil {
-		return 0, err
+		return path
 	}
-
-	// Estimate size
-	data, _ := xml.MarshalIndent(output, "", "  ")
-	return int64(len(data) + len(xml.Header)), nil
+	return relPath
 }
 
-func writeMarkdownOutput(fileInfos []FileInfo, writer io.Writer, stats Stats) (int64, error) {
-	totalBytes := int64(0)
-	bufWriter := bufio.NewWriter(writer)
+// vendoredDirs are the conventional names of directories holding third-party
+// dependencies across ecosystems, pruned by -exclude-vendored.
+var vendoredDirs = map[string]bool{
+	"vendor":           true, // Go, PHP (Composer), Ruby (bundler --path)
+	"node_modules":     true, // JavaScript
+	"bower_components": true, // JavaScript (Bower)
+	"jspm_packages":    true, // JavaScript (jspm)
+	"site-packages":    true, // Python
+	"dist-packages":    true, // Python (Debian)
+	"__pypackages__":   true, // Python (PEP 582)
+	".venv":            true, // Python virtualenv
+	"venv":             true, // Python virtualenv
+	"Pods":             true, // iOS (CocoaPods)
+	"Carthage":         true, // iOS (Carthage)
+	"third_party":      true, // Bazel / C++ convention
+	".bundle":          true, // Ruby
+	"deps":             true, // Elixir
+	"_deps":            true, // CMake FetchContent
+}
 
-	header := fmt.Sprintf("# Pecel Output\n\n")
-	header += fmt.Sprintf("**Generated**: %s  \n", time.Now().Format("2006-01-02 15:04:05"))
-	header += fmt.Sprintf("**Files**: %d | **Directories**: %d | **Total Size**: %s  \n\n",
-		stats.FilesProcessed, stats.Directories, formatBytes(stats.TotalBytes))
+// compileGlobs combines glob patterns into one regular expression over
+// slash-separated relative paths. Patterns use the syntax of globRegexp: "*",
+// "?", "[...]" and "[!...]" as in a shell, plus "**" for any number of
+// directories, and no braces. One without a slash matches the file name at
+// any depth.
+func compileGlobs(patterns []string) (*regexp.Regexp, error) {
+	var parts []string
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		glob := "**/" + pattern
+		if strings.Contains(pattern, "/") {
+			glob = strings.TrimPrefix(pattern, "/")
+		}
+		expr, err := globRegexp(glob)
+		if err == nil {
+			_, err = regexp.Compile(expr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", pattern, err)
+		}
+		parts = append(parts, "^"+expr+"$")
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no patterns given")
+	}
+	return regexp.Compile("(?:" + strings.Join(parts, ")|(?:") + ")")
+}
 
-	n, _ := bufWriter.WriteString(header)
-	totalBytes += int64(n)
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") ||
+		(strings.HasPrefix(name, "~") && len(name) > 1)
+}
 
-	for i, info := range fileInfos {
-		section := fmt.Sprintf("## File %d: `%s`\n\n", i+1, info.RelativePath)
-		section += fmt.Sprintf("**Size**: %s  \n", formatBytes(info.Size))
-		section += fmt.Sprintf("**Modified**: %s  \n\n", info.Modified)
-		section += "### Content\n```\n"
-		section += info.Content + "\n```\n\n"
-		section += "---\n\n"
+var ageComponentRegex = regexp.MustCompile(`(\d+(?:\.\d+)?)([a-zµ]+)`)
 
-		n, _ := bufWriter.WriteString(section)
-		totalBytes += int64(n)
+// parseAge parses an age such as "30d", "2w" or "1w3d12h". On top of the units
+// understood by time.ParseDuration it accepts d (days) and w (weeks).
+func parseAge(value string) (time.Duration, error) {
+	re := ageComponentRegex
+	rest := strings.TrimSpace(value)
+	if rest == "" || re.ReplaceAllString(rest, "") != "" {
+		return 0, fmt.Errorf("invalid age %q (expected e.g. 30d, 2w, 12h)", value)
 	}
 
-	footer := fmt.Sprintf("## Summary\n\n")
-	footer += fmt.Sprintf("- **Files processed**: %d\n", stats.FilesProcessed)
-	footer += fmt.Sprintf("- **Directories scanned**: %d\n", stats.Directories)
-	footer += fmt.Sprintf("- **Total input size**: %s\n", formatBytes(stats.TotalBytes))
-	footer += fmt.Sprintf("- **Processing time**: %.2f seconds\n", stats.Duration)
-
-	n, _ = bufWriter.WriteString(footer)
-	totalBytes += int64(n)
-
-	bufWriter.Flush()
-	return totalBytes, nil
+	var total time.Duration
+	for _, m := range re.FindAllStringSubmatch(rest, -1) {
+		num, _ := strconv.ParseFloat(m[1], 64)
+		switch m[2] {
+		case "d":
+			total += time.Duration(num * float64(24*time.Hour))
+		case "w":
+			total += time.Duration(num * float64(7*24*time.Hour))
+		default:
+			d, err := time.ParseDuration(m[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid age %q: unknown unit %q", value, m[2])
+			}
+			total += d
+		}
+	}
+	return total, nil
 }
 
-func printSummary(stats Stats, format string, compress, dryRun bool) {
-	fmt.Printf("\n%s %s\n", cyan("┌"), strings.Repeat("─", 50))
-	fmt.Printf("%s Processing Summary\n", cyan("│"))
-	fmt.Printf("%s %s\n", cyan("├"), strings.Repeat("─", 50))
-	fmt.Printf("%s Files processed:     %s\n", cyan("│"), green(strconv.Itoa(stats.FilesProcessed)))
-	fmt.Printf("%s Directories scanned: %s\n", cyan("│"), green(strconv.Itoa(stats.Directories)))
-	fmt.Printf("%s Total size:          %s\n", cyan("│"), green(formatBytes(stats.TotalBytes)))
-	fmt.Printf("%s Processing time:     %.2f seconds\n", cyan("│"), stats.Duration)
+// byteSizeUnits are the suffixes parseByteSize accepts, in powers of 1024
+// like formatBytes.
+var byteSizeUnits = map[string]int64{
+	"": 1, "B": 1,
+	"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+	"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+	"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+}
 
-	if !dryRun {
-		fmt.Printf("%s Output format:       %s\n", cyan("│"), green(format))
-		if compress {
-			fmt.Printf("%s Compression:         %s\n", cyan("│"), green("gzip"))
-		}
-		fmt.Printf("%s Output size:         %s\n", cyan("│"), green(formatBytes(stats.OutputSize)))
-		if stats.OutputSize > 0 {
-			ratio := float64(stats.OutputSize) / float64(stats.TotalBytes) * 100
-			fmt.Printf("%s Compression ratio:   %.1f%%\n", cyan("│"), ratio)
-		}
+// parseByteSize parses a size such as "10MB", "512K" or "1048576".
+func parseByteSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	num, err := strconv.ParseFloat(s[:i], 64)
+	unit, ok := byteSizeUnits[strings.TrimSpace(s[i:])]
+	if err != nil || !ok || num <= 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 10MB, 512KB)", value)
 	}
-	fmt.Printf("%s %s\n", cyan("└"), strings.Repeat("─", 50))
+	return int64(num * float64(unit)), nil
 }
 
-func loadConfig(filename string) (Config, error) {
-	var config Config
+// displayModified returns the modification time as shown in human-oriented
+// headers: the absolute timestamp, or its age when -relative-time is set.
+func displayModified(info FileInfo, config Config) string {
+	if config.RelativeTime && !info.modTime.IsZero() {
+		return humanizeTime(info.modTime, time.Now())
+	}
+	return info.Modified
+}
 
-	file, err := os.Open(filename)
-	if err != nil {
-		return config, err
+// humanizeTime formats t relative to now, e.g. "3 days ago" or "in 2 hours".
+func humanizeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < time.Minute {
+		return "just now"
 	}
-	defer file.Close()
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&config)
-	return config, err
+	var n int64
+	var unit string
+	switch {
+	case d < time.Hour:
+		n, unit = int64(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int64(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int64(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		n, unit = int64(d/(30*24*time.Hour)), "month"
+	default:
+		n, unit = int64(d/(365*24*time.Hour)), "year"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	if future {
+		return fmt.Sprintf("in %d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %s ago", n, unit)
 }
 
-func getRelativePath(path, baseDir string) string {
-	relPath, err := filepath.Rel(baseDir, path)
-	if err != nil {
-		return path
-	}
-	return relPath
+// countingWriter tracks how many bytes pass through to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
 }
 
-func isHidden(name string) bool {
-	return strings.HasPrefix(name, ".") ||
-		(strings.HasPrefix(name, "~") && len(name) > 1)
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
 }
 
 func formatBytes(bytes int64) string {
@@ -908,6 +3865,300 @@ func formatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// applyFlagOverrides overrides the settings of a configuration file with the
+// command line flags that were given, even when they were given their
+// default value. Every flag that sets a Config field belongs here.
+func applyFlagOverrides(config *Config) {
+	if isFlagSet("input") || isFlagSet("i") {
+		config.InputDir = *inputDir
+	}
+	if isFlagSet("output") || isFlagSet("o") {
+		config.OutputFile = *outputFile
+	}
+	if isFlagSet("ext") {
+		config.Extensions = splitFlagList(*extensions)
+	}
+	if isFlagSet("exclude-hidden") || isFlagSet("eh") {
+		config.ExcludeHidden = *excludeHidden
+	}
+	if isFlagSet("max-size") {
+		config.MaxFileSize = *maxFileSize
+	}
+	if isFlagSet("min-size") {
+		config.MinFileSize = *minFileSize
+	}
+	if isFlagSet("exclude") {
+		config.ExcludePattern = *excludePattern
+	}
+	if isFlagSet("include") {
+		config.IncludePattern = *includePattern
+	}
+	if isFlagSet("exclude-glob") {
+		config.ExcludeGlobs = splitFlagList(*excludeGlob)
+	}
+	if isFlagSet("include-glob") {
+		config.IncludeGlobs = splitFlagList(*includeGlob)
+	}
+	if isFlagSet("allow-list") {
+		config.AllowList = *allowList
+	}
+	if isFlagSet("deny-list") {
+		config.DenyList = *denyList
+	}
+	if isFlagSet("order-file") {
+		config.OrderFile = *orderFile
+	}
+	if isFlagSet("format") {
+		config.OutputFormat = *outputFormatFlag
+	}
+	if isFlagSet("compress") {
+		config.Compress = *compress
+	}
+	if isFlagSet("compress-workers") {
+		config.CompressWorkers = *compressWorkers
+	}
+	if isFlagSet("compression") {
+		config.Compression = *compression
+	}
+	if isFlagSet("compression-level") {
+		config.CompressionLevel = *compressionLevel
+	}
+	if isFlagSet("parallel") {
+		config.Parallel = *parallel
+	}
+	if isFlagSet("walk-parallel") {
+		config.WalkParallel = *walkParallel
+	}
+	if isFlagSet("read-order") {
+		config.ReadOrder = *readOrderFlag
+	}
+	if isFlagSet("read-rate-limit") {
+		config.ReadRateLimit = *readRateLimit
+	}
+	if isFlagSet("quiet") {
+		config.Quiet = *quiet
+	}
+	if isFlagSet("verbose") {
+		config.Verbose = *verbose
+	}
+	if isFlagSet("dry-run") {
+		config.DryRun = *dryRun
+	}
+	if isFlagSet("marshal-workers") {
+		config.MarshalWorkers = *marshalWorkers
+	}
+	if isFlagSet("json-pretty-threshold") {
+		config.JSONPrettyThreshold = *jsonPrettyThreshold
+	}
+	if isFlagSet("relative-time") {
+		config.RelativeTime = *relativeTime
+	}
+	if isFlagSet("plain-summary") {
+		config.PlainSummary = *plainSummary
+	}
+	if isFlagSet("touch-output-mtime") {
+		config.OutputMtime = *outputMtime
+	}
+	if isFlagSet("chunk-by-tokens") {
+		config.ChunkTokens = *chunkTokens
+	}
+	if isFlagSet("split-size") {
+		config.SplitSize = *splitSize
+	}
+	if isFlagSet("resume-parts") {
+		config.ResumeParts = *resumeParts
+	}
+	if isFlagSet("hash") {
+		config.Hash = *hashFiles
+	}
+	if isFlagSet("line-counts") {
+		config.LineCounts = *lineCounts
+	}
+	if isFlagSet("content-hash-only") {
+		config.ContentHashOnly = *contentHashOnly
+	}
+	if isFlagSet("path-prefix-lines") {
+		config.PathPrefixLines = *pathPrefixLines
+	}
+	if isFlagSet("go-api-only") {
+		config.GoAPIOnly = *goAPIOnly
+	}
+	if isFlagSet("detect-type") {
+		config.DetectType = *detectType
+	}
+	if isFlagSet("keep-bom") {
+		config.KeepBOM = *keepBOM
+	}
+	if isFlagSet("root-label") {
+		config.RootLabel = *rootLabel
+	}
+	if isFlagSet("hexdump-binary") {
+		config.HexdumpBinary = *hexdumpBinary
+	}
+	if isFlagSet("hexdump-width") {
+		config.HexdumpWidth = *hexdumpWidth
+	}
+	if isFlagSet("exclude-large-binary-automatically") {
+		config.KeepLargeBinaries = !*excludeLargeBinary
+	}
+	if isFlagSet("large-binary-threshold") {
+		config.LargeBinaryThreshold = *largeBinaryThresholdFlag
+	}
+	if isFlagSet("binary-threshold") {
+		config.BinaryThreshold = *binaryThresholdFlag
+	}
+	if isFlagSet("skip-binary") {
+		config.KeepBinaries = !*skipBinaryFlag
+	}
+	if isFlagSet("base64-binary") {
+		config.Base64Binary = *base64Binary
+	}
+	if isFlagSet("exclude-symlinks") {
+		config.ExcludeSymlinks = *excludeSymlinks
+	}
+	if isFlagSet("include-symlink-targets-as-metadata") {
+		config.SymlinkMetadata = *symlinkMetadata
+	}
+	if isFlagSet("follow-symlinks") {
+		config.FollowSymlinks = *followSymlinks
+	}
+	if isFlagSet("materialize-symlinks") {
+		config.MaterializeSymlinks = *materializeSymlinks
+	}
+	if isFlagSet("max-symlink-depth") {
+		config.MaxSymlinkDepth = *maxSymlinkDepthFlag
+	}
+	if isFlagSet("content-grep") {
+		config.ContentGrep = *contentGrep
+	}
+	if isFlagSet("snippet-lines") {
+		config.SnippetLines = *snippetLines
+	}
+	if isFlagSet("output-dir-mirror") {
+		config.OutputDirMirror = *outputDirMirror
+	}
+	if isFlagSet("externalize-content") {
+		config.ExternalizeContent = *externalizeContentDir
+	}
+	if isFlagSet("rename-extension") {
+		config.RenameExtensions = *renameExtensions
+	}
+	if isFlagSet("header-template-file") {
+		config.HeaderTemplateFile = *headerTemplateFile
+	}
+	if isFlagSet("footer-template-file") {
+		config.FooterTemplateFile = *footerTemplateFile
+	}
+	if isFlagSet("no-header") {
+		config.NoHeader = *noHeader
+	}
+	if isFlagSet("no-footer") {
+		config.NoFooter = *noFooter
+	}
+	if isFlagSet("max-output-lines") {
+		config.MaxOutputLines = *maxOutputLines
+	}
+	if isFlagSet("respect-editorconfig") {
+		config.RespectEditorConfig = *respectEditorConfig
+	}
+	if isFlagSet("markdown-block-lines") {
+		config.MarkdownBlockLines = *markdownBlockLines
+	}
+	if isFlagSet("lang-map") {
+		config.LangMap = *langMap
+	}
+	if isFlagSet("markdown-toc") {
+		config.MarkdownTOC = *markdownTOCFlag
+	}
+	if isFlagSet("csv-include-content") {
+		config.CSVIncludeContent = *csvIncludeContent
+	}
+	if isFlagSet("max-blank-lines") {
+		config.MaxBlankLines = *maxBlankLines
+	}
+	if isFlagSet("separator-width") {
+		config.SeparatorWidth = *separatorWidth
+	}
+	if isFlagSet("watch") {
+		config.Watch = *watchMode
+	}
+	if isFlagSet("content-max-depth") {
+		config.ContentMaxDepth = *contentMaxDepth
+	}
+	if isFlagSet("progress-file") {
+		config.ProgressFile = *progressFile
+	}
+	if isFlagSet("timeout") {
+		config.Timeout = *timeout
+	}
+	if isFlagSet("metrics-file") {
+		config.MetricsFile = *metricsFile
+	}
+	if isFlagSet("report-duplicates") {
+		config.ReportDuplicates = *reportDuplicates
+	}
+	if isFlagSet("group-summary") {
+		config.GroupSummary = *groupSummary
+	}
+	if isFlagSet("quarantine-oversize") {
+		config.QuarantineOversize = *quarantineOversize
+	}
+	if isFlagSet("git-author") {
+		config.GitAuthor = *gitAuthor
+	}
+	if isFlagSet("vanished-files") {
+		config.VanishedFiles = *vanishedFiles
+	}
+	if isFlagSet("compact-empty-sections") {
+		config.CompactEmptySections = *compactEmpty
+	}
+	if isFlagSet("number-files") {
+		config.NumberFiles = *numberFilesFlag
+	}
+	if isFlagSet("detect-secrets") {
+		config.DetectSecrets = *detectSecrets
+	}
+	if isFlagSet("secrets-action") {
+		config.SecretsAction = *secretsAction
+	}
+	if isFlagSet("manifest") {
+		config.Manifest = *manifest
+	}
+	if isFlagSet("verify-against-manifest") {
+		config.VerifyManifest = *verifyManifestPath
+	}
+	if isFlagSet("diff-against") {
+		config.DiffAgainst = *diffAgainst
+	}
+	if isFlagSet("newer-than") {
+		config.NewerThan = *newerThan
+	}
+	if isFlagSet("exclude-vendored") {
+		config.ExcludeVendored = *excludeVendored
+	}
+	if isFlagSet("gitignore") {
+		config.GitIgnore = *gitIgnore
+	}
+	if isFlagSet("exclude-tests") {
+		config.ExcludeTests = *excludeTests
+	}
+	if isFlagSet("dedup-by-name") {
+		config.DedupByName = *dedupName
+	}
+	if isFlagSet("older-than") {
+		config.OlderThan = *olderThan
+	}
+}
+
+// splitFlagList splits a comma-separated flag value; an empty value, given
+// to clear a list set in a configuration file, yields none.
+func splitFlagList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
 // Helper function to check if a flag was explicitly set
 func isFlagSet(name string) bool {
 	found := false
@@ -926,8 +4177,8 @@ func init() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
 
 		fmt.Fprintf(os.Stderr, "%s Basic Options:\n", cyan("📋"))
-		fmt.Fprintf(os.Stderr, "  -i, -input string        Input directory path (default \".\")\n")
-		fmt.Fprintf(os.Stderr, "  -o, -output string       Output file path (default \"combined.txt\")\n")
+		fmt.Fprintf(os.Stderr, "  -i, -input string        Input directory path, \"-\" for stdin, or sftp://user@host/path (default \".\")\n")
+		fmt.Fprintf(os.Stderr, "  -o, -output string       Output file path, s3:// / http(s):// URL, or \"-\" for stdout (default \"combined.txt\")\n")
 		fmt.Fprintf(os.Stderr, "  -ext string              Comma-separated list of file extensions\n")
 		fmt.Fprintf(os.Stderr, "  -eh, -exclude-hidden     Exclude hidden files (default true)\n")
 
@@ -936,22 +4187,117 @@ func init() {
 		fmt.Fprintf(os.Stderr, "  -min-size int            Minimum file size in bytes\n")
 		fmt.Fprintf(os.Stderr, "  -include string          Regex pattern to include files\n")
 		fmt.Fprintf(os.Stderr, "  -exclude string          Regex pattern to exclude files\n")
+		fmt.Fprintf(os.Stderr, "  -include-glob string     Comma-separated globs to include files (**/ spans directories)\n")
+		fmt.Fprintf(os.Stderr, "  -exclude-glob string     Comma-separated globs to exclude files (e.g. *.test.go)\n")
+		fmt.Fprintf(os.Stderr, "  -interactive-filter-test Preview kept/excluded files and tune -exclude/-include first\n")
+		fmt.Fprintf(os.Stderr, "  -scan-extensions         List files and bytes per extension found, then exit\n")
+		fmt.Fprintf(os.Stderr, "  -allow-list file         Include only the exact relative paths listed in file\n")
+		fmt.Fprintf(os.Stderr, "  -deny-list file          Exclude the exact relative paths listed in file\n")
+		fmt.Fprintf(os.Stderr, "  -order-file file         Put the relative paths listed in file first, in that order\n")
+		fmt.Fprintf(os.Stderr, "  -exclude-vendored        Skip vendored dependency directories (vendor, node_modules, ...)\n")
+		fmt.Fprintf(os.Stderr, "  -gitignore               Skip files matched by .gitignore files in the tree\n")
+		fmt.Fprintf(os.Stderr, "  -exclude-tests           Skip test files (_test.go, *.spec.ts, test_*.py, *Test.java, ...)\n")
+		fmt.Fprintf(os.Stderr, "  -dedup-by-name string    Keep one file per base name: first, largest or newest\n")
+		fmt.Fprintf(os.Stderr, "  -newer-than string       Only files modified within this age (e.g. 30d, 2w)\n")
+		fmt.Fprintf(os.Stderr, "  -older-than string       Only files modified before this age (e.g. 30d, 2w)\n")
+		fmt.Fprintf(os.Stderr, "  -hexdump-binary          Render binary files as a hex dump instead of raw bytes\n")
+		fmt.Fprintf(os.Stderr, "  -hexdump-width int       Bytes per hex dump line (default 16)\n")
+		fmt.Fprintf(os.Stderr, "  -exclude-large-binary-automatically\n")
+		fmt.Fprintf(os.Stderr, "                           Skip large files that look binary (default true)\n")
+		fmt.Fprintf(os.Stderr, "  -large-binary-threshold int\n")
+		fmt.Fprintf(os.Stderr, "                           Size cutoff for the large-binary guard (default 10 MB)\n")
+		fmt.Fprintf(os.Stderr, "  -binary-threshold float  Share of non-text bytes that makes a file binary (default 0.3)\n")
+		fmt.Fprintf(os.Stderr, "  -skip-binary             Skip files that look binary (default true)\n")
+		fmt.Fprintf(os.Stderr, "  -base64-binary           Include binary files base64-encoded instead of skipping them\n")
+		fmt.Fprintf(os.Stderr, "  -exclude-symlinks        Skip symbolic links instead of following them\n")
+		fmt.Fprintf(os.Stderr, "  -follow-symlinks         Walk into symlinked directories, skipping cycles\n")
+		fmt.Fprintf(os.Stderr, "  -materialize-symlinks    Include every symlink as a copy of its target under the link's path\n")
+		fmt.Fprintf(os.Stderr, "  -max-symlink-depth int   Symlink hops followed along one path before giving up (default 8)\n")
+		fmt.Fprintf(os.Stderr, "  -include-symlink-targets-as-metadata\n")
+		fmt.Fprintf(os.Stderr, "                           Record skipped symlinks and their targets without content\n")
+		fmt.Fprintf(os.Stderr, "  -detect-secrets          Warn about secrets (keys, tokens) found in file content\n")
+		fmt.Fprintf(os.Stderr, "  -secrets-action string   With -detect-secrets: warn, redact or abort (default \"warn\")\n")
+		fmt.Fprintf(os.Stderr, "  -content-grep string     Only include files whose content matches this regex\n")
+		fmt.Fprintf(os.Stderr, "  -snippet-lines int       With -content-grep, keep only N lines around each match\n")
 
 		fmt.Fprintf(os.Stderr, "\n%s Output Options:\n", cyan("📄"))
-		fmt.Fprintf(os.Stderr, "  -format string           Output format: text, json, xml, markdown (default \"text\")\n")
+		fmt.Fprintf(os.Stderr, "  -format string           Output format: text, json, xml, yaml, markdown, html-app, csv, sqlite (default \"text\")\n")
+		fmt.Fprintf(os.Stderr, "  -output-json-streaming-to-stdout\n")
+		fmt.Fprintf(os.Stderr, "                           Stream one JSON line per file to stdout; messages go to stderr\n")
 		fmt.Fprintf(os.Stderr, "  -compress                Compress output with gzip\n")
-		fmt.Fprintf(os.Stderr, "  -config string           Load configuration from JSON file\n")
+		fmt.Fprintf(os.Stderr, "  -compression string      Output compression: none, gzip or zstd\n")
+		fmt.Fprintf(os.Stderr, "  -compression-level int   1-9 for gzip, 1-22 for zstd (0 = codec default)\n")
+		fmt.Fprintf(os.Stderr, "  -compress-workers int    Parallel gzip workers for large outputs (0 = -parallel)\n")
+		fmt.Fprintf(os.Stderr, "  -touch-output-mtime str  Set output mtime: \"newest\" input, RFC 3339 time or Unix seconds\n")
+		fmt.Fprintf(os.Stderr, "  -chunk-by-tokens int     Split output into parts of at most N estimated tokens\n")
+		fmt.Fprintf(os.Stderr, "  -split-size size         Split output into parts of about this size, e.g. 10MB\n")
+		fmt.Fprintf(os.Stderr, "                           Parts are written as name.part1.ext, name.part2.ext, ...\n")
+		fmt.Fprintf(os.Stderr, "                           for -output name.ext, which itself is not written\n")
+		fmt.Fprintf(os.Stderr, "  -resume-parts            Keep parts an interrupted split run completed\n")
+		fmt.Fprintf(os.Stderr, "  -max-output-lines int    Cap text/markdown output at N lines, ending at a file boundary\n")
+		fmt.Fprintf(os.Stderr, "  -content-max-depth int   Read content only within N levels of the input; index deeper files\n")
+		fmt.Fprintf(os.Stderr, "  -header-template-file f  Go template for the document header (text, markdown, html-app)\n")
+		fmt.Fprintf(os.Stderr, "  -footer-template-file f  Go template for the document footer (text, markdown, html-app)\n")
+		fmt.Fprintf(os.Stderr, "  -no-header, -no-footer   Leave out the document header or summary footer\n")
+		fmt.Fprintf(os.Stderr, "  -compact-empty-sections  Show empty files as one line in text/markdown output\n")
+		fmt.Fprintf(os.Stderr, "  -number-files            Label sections \"File N of M\"; add index to JSON/XML\n")
+		fmt.Fprintf(os.Stderr, "  -separator-width int     Separator width in text output (default: terminal width, else 80)\n")
+		fmt.Fprintf(os.Stderr, "  -markdown-block-lines int\n")
+		fmt.Fprintf(os.Stderr, "                           Split markdown code blocks longer than N lines\n")
+		fmt.Fprintf(os.Stderr, "  -markdown-toc            Add a table of contents to markdown output\n")
+		fmt.Fprintf(os.Stderr, "  -csv-include-content     Add a content column to csv output\n")
+		fmt.Fprintf(os.Stderr, "  -lang-map list           Override fence languages, e.g. .vue=html,.tpl=jinja\n")
+		fmt.Fprintf(os.Stderr, "  -hash                    Record a SHA-256 hash of each file's content\n")
+		fmt.Fprintf(os.Stderr, "  -line-counts             Show each file's line count in its section header\n")
+		fmt.Fprintf(os.Stderr, "  -content-hash-only       Only hash files; write path/hash pairs instead of content\n")
+		fmt.Fprintf(os.Stderr, "  -diff-against string     Annotate changes relative to a previous JSON output\n")
+		fmt.Fprintf(os.Stderr, "  -git-author              Show each file's last commit author and date (git repos only)\n")
+		fmt.Fprintf(os.Stderr, "  -report-duplicates       List groups of identical files and the space they waste\n")
+		fmt.Fprintf(os.Stderr, "  -group-summary           Break the summary down by top-level directory\n")
+		fmt.Fprintf(os.Stderr, "  -quarantine-oversize     List the files -max-size leaves out, with sizes\n")
+		fmt.Fprintf(os.Stderr, "  -manifest string         Also write a sha256sum-style manifest of file hashes\n")
+		fmt.Fprintf(os.Stderr, "  -verify-against-manifest string\n")
+		fmt.Fprintf(os.Stderr, "                           Check the tree against a manifest; exit 1 on drift\n")
+		fmt.Fprintf(os.Stderr, "  -respect-editorconfig    Apply .editorconfig indentation, whitespace and final-newline rules\n")
+		fmt.Fprintf(os.Stderr, "  -path-prefix-lines       Prefix each content line with relpath:lineno:\n")
+		fmt.Fprintf(os.Stderr, "  -max-blank-lines int     Cap runs of consecutive blank lines at N\n")
+		fmt.Fprintf(os.Stderr, "  -go-api-only             Reduce .go files to exported declarations, without bodies\n")
+		fmt.Fprintf(os.Stderr, "  -keep-bom                Keep UTF-8 byte order marks instead of stripping them\n")
+		fmt.Fprintf(os.Stderr, "  -detect-type             Detect content types from file bytes (sets content_type)\n")
+		fmt.Fprintf(os.Stderr, "  -output-dir-mirror dir   Write each transformed file under dir instead of combining\n")
+		fmt.Fprintf(os.Stderr, "  -externalize-content dir Store contents as dir/<sha256> blobs referenced from JSON/XML\n")
+		fmt.Fprintf(os.Stderr, "  -rename-extension list   Rewrite mirrored extensions, e.g. jsx=js,tsx=ts\n")
+		fmt.Fprintf(os.Stderr, "  -root-label string       Prefix every relative path with a label (e.g. project name)\n")
+		fmt.Fprintf(os.Stderr, "  -relative-time           Show modification times as ages (\"3 days ago\") in text/markdown\n")
+		fmt.Fprintf(os.Stderr, "  -config string           Load configuration from a JSON or YAML file (repeatable; later\n")
+		fmt.Fprintf(os.Stderr, "                           files override every setting they give, false and 0 included)\n")
+		fmt.Fprintf(os.Stderr, "  -no-config               Don't discover .pecel.json/.pecel.yaml in parent directories\n")
+		fmt.Fprintf(os.Stderr, "  -profile string          Apply a named profile from the configuration file\n")
 
 		fmt.Fprintf(os.Stderr, "\n%s Performance Options:\n", cyan("⚡"))
-		fmt.Fprintf(os.Stderr, "  -parallel int            Number of files to process in parallel (default 1)\n")
+		fmt.Fprintf(os.Stderr, "  -parallel int            Number of files to process in parallel (default: number of CPUs; 1 = sequential)\n")
+		fmt.Fprintf(os.Stderr, "  -vanished-files string   Files removed after the scan: info, ignore or error (default \"info\")\n")
+		fmt.Fprintf(os.Stderr, "  -read-rate-limit int     Maximum bytes per second read across all workers (0 = unlimited)\n")
+		fmt.Fprintf(os.Stderr, "  -read-order string       Order files are read in: directory, size or path (default \"directory\")\n")
+		fmt.Fprintf(os.Stderr, "  -walk-parallel int       Number of directories read in parallel during discovery (default 1)\n")
+		fmt.Fprintf(os.Stderr, "  -marshal-workers int     Workers marshaling JSON entries for large runs (0 = sequential)\n")
+		fmt.Fprintf(os.Stderr, "  -json-pretty-threshold string\n")
+		fmt.Fprintf(os.Stderr, "                           Write compact JSON above N content bytes or \"Nfiles\" files\n")
 
 		fmt.Fprintf(os.Stderr, "\n%s Mode Options:\n", cyan("🎯"))
 		fmt.Fprintf(os.Stderr, "  -dry-run                 Show what would be processed without writing\n")
+		fmt.Fprintf(os.Stderr, "  -watch                   Rebuild when input files change; content-identical saves are ignored\n")
 		fmt.Fprintf(os.Stderr, "  -quiet                   Suppress non-essential output\n")
 		fmt.Fprintf(os.Stderr, "  -verbose                 Show detailed progress\n")
+		fmt.Fprintf(os.Stderr, "  -progress-file string    Write JSON progress updates to a file or named pipe\n")
+		fmt.Fprintf(os.Stderr, "  -timeout duration        Stop after this long and write the files processed so far (exit 124)\n")
+		fmt.Fprintf(os.Stderr, "  -metrics-file string     Write run stats in Prometheus textfile format (for node_exporter)\n")
+		fmt.Fprintf(os.Stderr, "  -plain-summary           Print the summary without box-drawing characters\n")
+		fmt.Fprintf(os.Stderr, "  -no-color                Print messages without colors (also NO_COLOR)\n")
 
 		fmt.Fprintf(os.Stderr, "\n%s Information Options:\n", cyan("ℹ️"))
 		fmt.Fprintf(os.Stderr, "  -v, -version             Show version information\n")
+		fmt.Fprintf(os.Stderr, "  -list-formats            List supported output formats and exit\n")
 		fmt.Fprintf(os.Stderr, "  -h, -help                Show this help message\n")
 
 		fmt.Fprintf(os.Stderr, "\n%s Examples:\n", cyan("🚀"))
@@ -959,7 +4305,10 @@ func init() {
 		fmt.Fprintf(os.Stderr, "  %s -ext .go,.txt -format json -compress\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -max-size 1000000 -parallel 4 -verbose\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -exclude \"\\.git|node_modules\" -dry-run\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -format json -o today.json -diff-against yesterday.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  cat notes.txt | %s -i - -format markdown\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -config config.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -config config.json -profile go-review\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -v\n", os.Args[0])
 	}
 }
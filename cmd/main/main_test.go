@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain lets tests run the pecel command itself: the test binary started
+// with PECEL_TEST_MAIN set runs main with its arguments instead of the tests.
+func TestMain(m *testing.M) {
+	if os.Getenv("PECEL_TEST_MAIN") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// pecelCommand returns a command that runs pecel with args.
+func pecelCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "PECEL_TEST_MAIN=1")
+	return cmd
+}
+
+// writeTree creates files, keyed by slash-separated path, under dir.
+func writeTree(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// writeManifest writes a sha256sum-style manifest of fileInfos to path.
+func writeManifest(path string, fileInfos []FileInfo) error {
+	return os.WriteFile(path, []byte(formatManifest(fileInfos)), 0644)
+}
+
+// formatManifest renders one "<hash>  <relative path>" line per hashed file,
+// sorted by path.
+func formatManifest(fileInfos []FileInfo) string {
+	lines := make([]string, 0, len(fileInfos))
+	for _, info := range fileInfos {
+		if info.Hash == "" {
+			continue
+		}
+		lines = append(lines, info.Hash+"  "+info.RelativePath)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i][64:] < lines[j][64:]
+	})
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// writeHashMap writes fileInfos as one JSON object mapping each relative path
+// to its hash, for -content-hash-only with -format json.
+func writeHashMap(fileInfos []FileInfo, w io.Writer) (int64, error) {
+	hashes := make(map[string]string, len(fileInfos))
+	for _, info := range fileInfos {
+		if info.Hash != "" {
+			hashes[info.RelativePath] = info.Hash
+		}
+	}
+	data, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// loadManifest reads a manifest written by -manifest, or the files of a JSON
+// bundle, as entries carrying a relative path and a hash.
+func loadManifest(path string) ([]FileInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return loadPreviousBundle(path)
+	}
+
+	var entries []FileInfo
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" {
+			continue
+		}
+		hash, relPath, ok := strings.Cut(text, "  ")
+		if !ok || len(hash) != 64 {
+			return nil, fmt.Errorf("%s:%d: expected \"<sha256>  <path>\"", path, line)
+		}
+		entries = append(entries, FileInfo{RelativePath: relPath, Hash: hash})
+	}
+	return entries, scanner.Err()
+}
+
+// verifyManifest re-scans the tree, compares it with the manifest at
+// config.VerifyManifest and prints every added, modified and missing file. It
+// reports whether the tree has drifted from the manifest.
+func verifyManifest(config Config, excludeRegex, includeRegex *regexp.Regexp) (bool, error) {
+	expected, err := loadManifest(config.VerifyManifest)
+	if err != nil {
+		return false, fmt.Errorf("loading manifest: %w", err)
+	}
+
+	var stats Stats
+	entries, err := collectFiles(context.Background(), config, excludeRegex, includeRegex, &stats)
+	if err != nil {
+		return false, fmt.Errorf("walking directory: %w", err)
+	}
+	var current []FileInfo
+	if config.Parallel > 1 {
+		current = processFilesParallel(context.Background(), entries, config, &stats)
+	} else {
+		current = processFilesSequential(context.Background(), entries, config, &stats)
+	}
+
+	summary := diffBundles(current, expected)
+	sort.Slice(current, func(i, j int) bool {
+		return current[i].RelativePath < current[j].RelativePath
+	})
+	for _, info := range current {
+		switch info.DiffStatus {
+		case diffAdded:
+			fmt.Printf("%s added:    %s\n", yellow("+"), info.RelativePath)
+		case diffModified:
+			fmt.Printf("%s changed:  %s\n", yellow("~"), info.RelativePath)
+		}
+	}
+	for _, path := range summary.Removed {
+		fmt.Printf("%s missing:  %s\n", red("-"), path)
+	}
+
+	drift := summary.Added > 0 || summary.Modified > 0 || len(summary.Removed) > 0
+	if drift {
+		fmt.Printf("\n%s %d added, %d changed, %d missing, %d unchanged\n", red("✗"),
+			summary.Added, summary.Modified, len(summary.Removed), summary.Unchanged)
+	} else {
+		fmt.Printf("%s All %d files match %s\n", green("✓"), summary.Unchanged, config.VerifyManifest)
+	}
+	return drift, nil
+}
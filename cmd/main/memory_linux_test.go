@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// TestStreamedOutputBoundedMemory bundles a 2 GiB tree in each streamed
+// format and checks that the peak resident memory of the run stays far below
+// the size of the input. The tree is 2048 hard links to one 1 MiB text file,
+// so it takes 1 MiB of disk.
+func TestStreamedOutputBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("reads 2 GiB per format")
+	}
+	const (
+		fileSize  = 1 << 20
+		fileCount = 2048
+		// maxRSS leaves room for the files in flight on four workers, their
+		// rendered copies and the garbage collector's headroom
+		maxRSS = 256 << 20
+	)
+
+	dir := t.TempDir()
+	line := "the quick brown fox jumps over the lazy dog, streamed\n"
+	seed := filepath.Join(dir, "seed.txt")
+	content := strings.Repeat(line, fileSize/len(line)+1)[:fileSize-1] + "\n"
+	if err := os.WriteFile(seed, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "input")
+	for i := 0; i < fileCount; i++ {
+		sub := filepath.Join(input, fmt.Sprintf("d%02d", i%32))
+		if err := os.MkdirAll(sub, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Link(seed, filepath.Join(sub, fmt.Sprintf("f%04d.txt", i))); err != nil {
+			t.Skipf("hard links unavailable: %v", err)
+		}
+	}
+
+	for _, format := range []string{"text", "markdown", "json", "xml"} {
+		t.Run(format, func(t *testing.T) {
+			cmd := pecelCommand("-i", input, "-o", os.DevNull, "-format", format, "-parallel", "4", "-quiet")
+			cmd.Stdout, cmd.Stderr = io.Discard, io.Discard
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("pecel: %v", err)
+			}
+			// Maxrss is in kilobytes on Linux
+			rss := cmd.ProcessState.SysUsage().(*syscall.Rusage).Maxrss << 10
+			if rss > maxRSS {
+				t.Errorf("peak RSS %d MiB for %d MiB of input, want at most %d MiB",
+					rss>>20, fileCount*fileSize>>20, maxRSS>>20)
+			}
+			t.Logf("peak RSS %d MiB for %d MiB of input", rss>>20, fileCount*fileSize>>20)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// writeMetrics writes the run's stats to path in the Prometheus text format
+// read by node_exporter's textfile collector. The file is replaced atomically,
+// as the collector requires, so a scrape never sees a partial write. runErr is
+// the error the run failed with, if any.
+func writeMetrics(path string, stats Stats, runErr error) error {
+	success := 1
+	if runErr != nil {
+		success = 0
+	}
+
+	var b strings.Builder
+	metric := func(name, help string, value interface{}) {
+		fmt.Fprintf(&b, "# HELP pecel_%s %s\n# TYPE pecel_%s gauge\npecel_%s %v\n", name, help, name, name, value)
+	}
+	metric("files_processed", "Files included in the last run.", stats.FilesProcessed)
+	metric("directories_scanned", "Directories walked in the last run.", stats.Directories)
+	metric("input_bytes", "Total size of the files included in the last run.", stats.TotalBytes)
+	metric("output_bytes", "Size of the output written by the last run.", stats.OutputSize)
+	metric("duration_seconds", "Wall-clock duration of the last run.", stats.Duration)
+	metric("file_errors", "Files that could not be processed in the last run.", stats.Errors)
+	metric("files_vanished", "Files removed between the scan and the read in the last run.", stats.Vanished)
+	metric("binaries_skipped", "Files left out for looking binary in the last run.", stats.BinariesSkipped+len(stats.LargeBinarySkipped))
+	metric("files_path_too_long", "Files skipped because their path was too long in the last run.", stats.PathTooLong)
+	metric("last_run_success", "Whether the last run completed without error (1) or failed (0).", success)
+	metric("last_run_timestamp_seconds", "Unix time the last run finished.", time.Now().Unix())
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pecel-metrics-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	// CreateTemp makes the file private; the collector may run as another user
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// recordMetrics writes -metrics-file at the end of a run, successful or not.
+// Failing to write metrics is reported but does not fail the run.
+func recordMetrics(config Config, stats *Stats, startTime time.Time, runErr error) {
+	if config.MetricsFile == "" {
+		return
+	}
+	if stats.Duration == 0 {
+		stats.Duration = time.Since(startTime).Seconds()
+	}
+	if err := writeMetrics(config.MetricsFile, *stats, runErr); err != nil {
+		fmt.Printf("%s Error writing -metrics-file: %v\n", red("✗"), err)
+	}
+}
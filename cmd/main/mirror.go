@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// parseExtensionRenames parses a -rename-extension value such as
+// "jsx=js,tsx=ts" into a map from old to new extension, both with their
+// leading dot.
+func parseExtensionRenames(value string) (map[string]string, error) {
+	renames := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("%q is not of the form from=to", pair)
+		}
+		renames["."+strings.TrimPrefix(from, ".")] = "." + strings.TrimPrefix(to, ".")
+	}
+	return renames, nil
+}
+
+// mirrorPath returns where a file is written under the -output-dir-mirror
+// directory: the same path it has relative to the input directory, with its
+// extension rewritten by -rename-extension.
+func mirrorPath(info FileInfo, config Config) (string, error) {
+	rel := "stdin"
+	if info.Path != stdinInput {
+		rel = getRelativePath(info.Path, config.InputDir)
+	}
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is outside the input directory", info.Path)
+	}
+	if to, ok := config.extensionRenames[filepath.Ext(rel)]; ok {
+		rel = strings.TrimSuffix(rel, filepath.Ext(rel)) + to
+	}
+	return filepath.Join(config.OutputDirMirror, rel), nil
+}
+
+// writeMirror writes each file's transformed content to its own file under
+// config.OutputDirMirror, recreating the input's directory structure instead
+// of combining everything into one output. Symlinks recorded as metadata have
+// no content and are not written. It returns the number of bytes written.
+func writeMirror(fileInfos []FileInfo, config Config) (int64, error) {
+	var written int64
+	// Renamed extensions can send two files to the same place
+	sources := make(map[string]string)
+	for _, info := range fileInfos {
+		if info.LinkTarget != "" {
+			continue
+		}
+		target, err := mirrorPath(info, config)
+		if err != nil {
+			return written, err
+		}
+		if other, ok := sources[target]; ok {
+			return written, fmt.Errorf("%s and %s would both be written to %s", other, info.RelativePath, target)
+		}
+		sources[target] = info.RelativePath
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(target, []byte(info.Content), 0644); err != nil {
+			return written, err
+		}
+		written += int64(len(info.Content))
+	}
+	return written, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// OversizeFile is a file left out for exceeding -max-size.
+type OversizeFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// sortOversize puts the largest files first, then orders by path.
+func sortOversize(files []OversizeFile) {
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Size != files[j].Size {
+			return files[i].Size > files[j].Size
+		}
+		return files[i].Path < files[j].Path
+	})
+}
+
+// printOversize lists the files -max-size left out under the summary, so the
+// limit can be judged against what it excluded.
+func printOversize(files []OversizeFile, limit int64) {
+	if len(files) == 0 {
+		fmt.Printf("\n%s No files exceeded -max-size (%s)\n", green("✓"), formatBytes(limit))
+		return
+	}
+	var total int64
+	for _, file := range files {
+		total += file.Size
+	}
+	fmt.Printf("\n%s %d files over -max-size (%s), %s in total:\n",
+		yellow("⚠"), len(files), formatBytes(limit), formatBytes(total))
+	for _, file := range files {
+		fmt.Printf("  %10s  %s\n", formatBytes(file.Size), file.Path)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// TestProcessFilesParallelCountsEveryFile runs 1000 files through 8 walk
+// and 8 read workers; run it with -race to check the Stats bookkeeping.
+func TestProcessFilesParallelCountsEveryFile(t *testing.T) {
+	const fileCount = 1000
+	dir := t.TempDir()
+	files := make(map[string]string, fileCount)
+	var totalBytes int64
+	for i := 0; i < fileCount; i++ {
+		content := fmt.Sprintf("file %d\n", i)
+		files[fmt.Sprintf("d%02d/f%04d.txt", i%25, i)] = content
+		totalBytes += int64(len(content))
+	}
+	writeTree(t, dir, files)
+
+	config := defaultConfig()
+	config.InputDir = dir
+	config.Quiet = true
+	config.Parallel = 8
+	config.WalkParallel = 8
+
+	var stats Stats
+	entries, err := collectFiles(context.Background(), config, nil, nil, &stats)
+	if err != nil {
+		t.Fatalf("collectFiles: %v", err)
+	}
+	if len(entries) != fileCount {
+		t.Fatalf("walk found %d files, want %d", len(entries), fileCount)
+	}
+
+	infos := processFilesParallel(context.Background(), entries, config, &stats)
+	if stats.FilesProcessed != fileCount {
+		t.Errorf("FilesProcessed = %d, want %d", stats.FilesProcessed, fileCount)
+	}
+	if stats.TotalBytes != totalBytes {
+		t.Errorf("TotalBytes = %d, want %d", stats.TotalBytes, totalBytes)
+	}
+	if stats.Errors != 0 {
+		t.Errorf("Errors = %d, want 0", stats.Errors)
+	}
+	if len(infos) != fileCount {
+		t.Fatalf("got %d results, want %d", len(infos), fileCount)
+	}
+	for i, info := range infos {
+		if info.Path != entries[i].Path {
+			t.Fatalf("result %d is %s, want walk order (%s)", i, info.Path, entries[i].Path)
+		}
+	}
+}
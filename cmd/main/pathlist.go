@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// readPathLines reads a file of relative paths, one per line, written with
+// forward slashes on any platform. Blank lines and lines starting with # are
+// ignored. Paths are returned cleaned and in native form.
+func readPathLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, filepath.Clean(filepath.FromSlash(line)))
+	}
+	return paths, scanner.Err()
+}
+
+// loadPathList reads a -allow-list or -deny-list file as a set of paths.
+func loadPathList(path string) (map[string]bool, error) {
+	lines, err := readPathLines(path)
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		paths[line] = true
+	}
+	return paths, nil
+}
+
+// loadOrderFile reads a -order-file as each listed path's position. A path
+// listed twice keeps its first position.
+func loadOrderFile(path string) (map[string]int, error) {
+	lines, err := readPathLines(path)
+	if err != nil {
+		return nil, err
+	}
+	order := make(map[string]int, len(lines))
+	for i, line := range lines {
+		if _, ok := order[line]; !ok {
+			order[line] = i
+		}
+	}
+	return order, nil
+}
+
+// applyOrder moves the files listed in order to the front, in the listed
+// sequence, and keeps the rest after them in their current order.
+func applyOrder(fileInfos []FileInfo, order map[string]int, config Config) {
+	rank := func(info FileInfo) int {
+		if i, ok := order[getRelativePath(info.Path, config.InputDir)]; ok {
+			return i
+		}
+		return len(order)
+	}
+	sort.SliceStable(fileInfos, func(i, j int) bool {
+		return rank(fileInfos[i]) < rank(fileInfos[j])
+	})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// progressInterval bounds how often -progress-file is rewritten.
+const progressInterval = 250 * time.Millisecond
+
+// progressSnapshot is the JSON document written to -progress-file.
+type progressSnapshot struct {
+	Done  int     `json:"done"`
+	Total int     `json:"total"`
+	Bytes int64   `json:"bytes"`
+	ETA   float64 `json:"eta"` // estimated seconds remaining
+}
+
+// progressReporter publishes processing progress to a file or named pipe for
+// other tools to display. Methods are safe for concurrent use and do nothing
+// on a nil reporter.
+type progressReporter struct {
+	path string
+
+	mu      sync.Mutex
+	started time.Time
+	written time.Time
+	current progressSnapshot
+}
+
+func newProgressReporter(path string) *progressReporter {
+	return &progressReporter{path: path}
+}
+
+// begin resets the counters for a run over total files.
+func (p *progressReporter) begin(total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started = time.Now()
+	p.current = progressSnapshot{Total: total}
+	p.write()
+}
+
+// fileDone records one processed file of the given size, publishing the new
+// state if the last update is older than progressInterval.
+func (p *progressReporter) fileDone(size int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current.Done++
+	p.current.Bytes += size
+	if time.Since(p.written) >= progressInterval {
+		p.write()
+	}
+}
+
+// finish publishes the final state regardless of the interval.
+func (p *progressReporter) finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current.Done = p.current.Total
+	p.write()
+}
+
+// write publishes the current snapshot; p.mu must be held. Failures are
+// ignored: progress reporting never fails a run.
+func (p *progressReporter) write() {
+	p.written = time.Now()
+	snapshot := p.current
+	if snapshot.Done > 0 && snapshot.Done < snapshot.Total {
+		perFile := time.Since(p.started).Seconds() / float64(snapshot.Done)
+		snapshot.ETA = perFile * float64(snapshot.Total-snapshot.Done)
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	// A named pipe gets one line per update, and only while someone is
+	// reading; opening it without a reader would otherwise block.
+	if info, err := os.Stat(p.path); err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+		f, err := os.OpenFile(p.path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			return
+		}
+		f.Write(data)
+		f.Close()
+		return
+	}
+
+	// Replace regular files atomically so readers never see a partial update
+	tmp, err := os.CreateTemp(filepath.Dir(p.path), ".pecel-progress-*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), p.path) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// progressBarInterval bounds how often the progress bar is redrawn.
+const progressBarInterval = 100 * time.Millisecond
+
+// progressBarWidth is the number of cells in the bar itself.
+const progressBarWidth = 30
+
+// progressBar draws a single updating status line with files done, the
+// percentage, throughput and ETA through a console. It replaces the periodic
+// progress lines when stdout is a terminal. add is safe for concurrent use
+// and does nothing on a nil bar.
+type progressBar struct {
+	console *console
+	total   int
+	started time.Time
+
+	mu    sync.Mutex
+	done  int
+	drawn time.Time
+}
+
+// startProgressBar returns the bar for a run over total files printing
+// through config.console, or nil when progress is printed as lines instead:
+// with -quiet or -verbose, or when stdout is not a terminal.
+func startProgressBar(total int, config Config) *progressBar {
+	if config.Quiet || config.Verbose || config.console == nil || total == 0 ||
+		!term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil
+	}
+	return &progressBar{console: config.console, total: total, started: time.Now()}
+}
+
+// add records one handled file, redrawing the bar if the last redraw is
+// older than progressBarInterval or this was the last file.
+func (b *progressBar) add() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done++
+	if b.done < b.total && time.Since(b.drawn) < progressBarInterval {
+		return
+	}
+	b.drawn = time.Now()
+	b.console.setStatus(b.line())
+}
+
+// line renders the status line; b.mu must be held.
+func (b *progressBar) line() string {
+	filled := progressBarWidth * b.done / b.total
+	elapsed := time.Since(b.started).Seconds()
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(b.done) / elapsed
+	}
+	eta := "--"
+	if b.done == b.total {
+		eta = "0s"
+	} else if rate > 0 {
+		eta = (time.Duration(float64(b.total-b.done)/rate) * time.Second).String()
+	}
+	return fmt.Sprintf("[%s%s] %d/%d (%.1f%%) %.1f files/s ETA %s",
+		strings.Repeat("#", filled), strings.Repeat(".", progressBarWidth-filled),
+		b.done, b.total, float64(b.done)/float64(b.total)*100, rate, eta)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// readRateChunk is the most a throttled read asks for at once, so large files
+// are read at an even pace rather than in one burst followed by a long pause.
+const readRateChunk = 64 * 1024
+
+// rateLimiter is a token bucket shared by every worker, so -read-rate-limit
+// bounds the combined read rate. Tokens are bytes. A caller may take more
+// tokens than are available; the balance goes negative and the caller sleeps
+// until the bucket would have refilled, which keeps concurrent readers fair.
+type rateLimiter struct {
+	rate  float64 // bytes per second
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	burst := float64(readRateChunk)
+	return &rateLimiter{rate: float64(bytesPerSecond), burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait blocks until n more bytes may be read.
+func (l *rateLimiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	time.Sleep(delay)
+}
+
+// throttledReader paces reads from r through a rateLimiter.
+type throttledReader struct {
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (t throttledReader) Read(p []byte) (int, error) {
+	if len(p) > readRateChunk {
+		p = p[:readRateChunk]
+	}
+	t.limiter.wait(len(p))
+	return t.r.Read(p)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// Orders in which files are read (-read-order).
+const (
+	readOrderDirectory = "directory"
+	readOrderSize      = "size"
+	readOrderPath      = "path"
+)
+
+// validateReadOrder checks a -read-order value; empty means the default.
+func validateReadOrder(order string) error {
+	switch order {
+	case "", readOrderDirectory, readOrderSize, readOrderPath:
+		return nil
+	}
+	return fmt.Errorf("unknown read order %q (want directory, size or path)", order)
+}
+
+// readOrder returns the indexes of entries in the order they should be read:
+//
+//   - directory (the default) reads each directory's files together, so the
+//     filesystem's caches for one directory are used before moving on, and
+//     the largest files of each directory first, to balance the workers;
+//   - size reads the largest files first, the longest-processing-time-first
+//     order that balances load across -parallel workers;
+//   - path reads in plain lexical path order.
+func readOrder(entries []walkEntry, order string) []int {
+	indexes := make([]int, len(entries))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	var less func(a, b walkEntry) bool
+	switch order {
+	case readOrderSize:
+		less = func(a, b walkEntry) bool { return a.Size > b.Size }
+	case readOrderPath:
+		less = func(a, b walkEntry) bool { return a.Path < b.Path }
+	default:
+		less = func(a, b walkEntry) bool {
+			if dirA, dirB := filepath.Dir(a.Path), filepath.Dir(b.Path); dirA != dirB {
+				return dirA < dirB
+			}
+			return a.Size > b.Size
+		}
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return less(entries[indexes[i]], entries[indexes[j]])
+	})
+	return indexes
+}
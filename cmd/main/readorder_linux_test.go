@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// BenchmarkReadOrder reads a tree of 2000 files in 50 directories in each
+// -read-order, buffered and streamed, with the files dropped from the page
+// cache before every iteration so the reads go to the disk.
+func BenchmarkReadOrder(b *testing.B) {
+	dir := b.TempDir()
+	files := make(map[string]string)
+	for d := 0; d < 50; d++ {
+		for f := 0; f < 40; f++ {
+			// The m subdirectories sort between the a and n files, so the
+			// walk leaves and re-enters each directory
+			prefix := "a"
+			if f%2 == 1 {
+				prefix = "n"
+			}
+			name := fmt.Sprintf("d%02d/%s%02d.txt", d, prefix, f)
+			if f%10 == 5 {
+				name = fmt.Sprintf("d%02d/m/%02d.txt", d, f)
+			}
+			files[name] = strings.Repeat("x", 4<<10+f*1<<10)
+		}
+	}
+	writeTree(b, dir, files)
+
+	config := defaultConfig()
+	config.InputDir = dir
+	config.OutputFile = os.DevNull
+	config.Quiet = true
+	config.Parallel = 4
+	var walked Stats
+	entries, err := collectFiles(context.Background(), config, nil, nil, &walked)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, order := range []string{readOrderDirectory, readOrderSize, readOrderPath} {
+		config := config
+		config.ReadOrder = order
+		b.Run("buffered/"+order, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				dropPageCache(b, entries)
+				b.StartTimer()
+				var stats Stats
+				processFilesParallel(context.Background(), entries, config, &stats)
+			}
+		})
+		b.Run("streamed/"+order, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				dropPageCache(b, entries)
+				b.StartTimer()
+				var stats Stats
+				if _, _, _, err := writeStreamedOutput(context.Background(), entries, config, &stats, walked); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// dropPageCache asks the kernel to evict the cached pages of entries.
+func dropPageCache(b *testing.B, entries []walkEntry) {
+	b.Helper()
+	for _, entry := range entries {
+		f, err := os.Open(entry.Path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		err = unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_DONTNEED)
+		f.Close()
+		if err != nil {
+			b.Skipf("fadvise: %v", err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// BenchmarkDispatchOrder reads a tree of 2000 tiny files and four 8 MiB ones,
+// each at the end of its directory, dispatching in walk order (path) and
+// largest first (size, and directory within each directory), buffered and
+// streamed. With walk order the huge files start last and one worker is
+// left finishing them alone.
+func BenchmarkDispatchOrder(b *testing.B) {
+	dir := b.TempDir()
+	files := make(map[string]string)
+	for d := 0; d < 4; d++ {
+		for f := 0; f < 500; f++ {
+			files[fmt.Sprintf("d%d/f%03d.txt", d, f)] = strings.Repeat("tiny\n", 50)
+		}
+		files[fmt.Sprintf("d%d/z-huge.txt", d)] = strings.Repeat("huge line of text\n", 8<<20/18)
+	}
+	writeTree(b, dir, files)
+
+	config := defaultConfig()
+	config.InputDir = dir
+	config.OutputFile = os.DevNull
+	config.Quiet = true
+	config.Parallel = 4
+	var walked Stats
+	entries, err := collectFiles(context.Background(), config, nil, nil, &walked)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, order := range []string{readOrderPath, readOrderSize, readOrderDirectory} {
+		config := config
+		config.ReadOrder = order
+		b.Run("buffered/"+order, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var stats Stats
+				processFilesParallel(context.Background(), entries, config, &stats)
+			}
+		})
+		b.Run("streamed/"+order, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var stats Stats
+				if _, _, _, err := writeStreamedOutput(context.Background(), entries, config, &stats, walked); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamWindowEnd(t *testing.T) {
+	sized := func(sizes ...int64) []walkEntry {
+		entries := make([]walkEntry, len(sizes))
+		for i, size := range sizes {
+			entries[i].Size = size
+		}
+		return entries
+	}
+	const mib = 1 << 20
+	for _, tc := range []struct {
+		name       string
+		entries    []walkEntry
+		start, min int
+		want       int
+	}{
+		{"small files fill the window", sized(mib, mib, mib, mib), 0, 2, 4},
+		{"the byte bound ends it", sized(20*mib, 10*mib, 5*mib, mib), 0, 1, 2},
+		{"at least min entries", sized(40*mib, 40*mib, 40*mib), 0, 2, 2},
+		{"from start", sized(40*mib, mib, mib), 1, 1, 3},
+		{"empty tail", sized(mib), 1, 2, 1},
+	} {
+		if got := streamWindowEnd(tc.entries, tc.start, tc.min); got != tc.want {
+			t.Errorf("%s: streamWindowEnd = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io"
+
+	"github.com/bhangun/pecel/pkg/pecel"
+)
+
+// availableFormats returns the built-in formats followed by the ones added
+// with pecel.RegisterFormat, in the order they are offered to users.
+func availableFormats() []outputFormat {
+	formats := append([]outputFormat(nil), outputFormats...)
+	for _, name := range pecel.Formats() {
+		formats = append(formats, outputFormat{name, "Registered output format", ""})
+	}
+	return formats
+}
+
+// writeRegisteredFormat renders fileInfos with a writer added through
+// pecel.RegisterFormat, handing it the library's view of files and stats.
+func writeRegisteredFormat(writer pecel.FormatWriter, fileInfos []FileInfo, w io.Writer, stats Stats) (int64, error) {
+	files := make([]pecel.FileInfo, len(fileInfos))
+	for i, info := range fileInfos {
+		files[i] = pecel.FileInfo{
+			Path:           info.Path,
+			Size:           info.Size,
+			Modified:       info.Modified,
+			Content:        info.Content,
+			RelativePath:   info.RelativePath,
+			Hash:           info.Hash,
+			DiffStatus:     info.DiffStatus,
+			ContentType:    info.ContentType,
+			LinkTarget:     info.LinkTarget,
+			ContentOmitted: info.ContentOmitted,
+			ContentRef:     info.ContentRef,
+			LineCount:      info.LineCount,
+			Index:          info.Index,
+			Encoding:       info.Encoding,
+			LastAuthor:     info.LastAuthor,
+			LastCommitDate: info.LastCommitDate,
+		}
+	}
+	return writer.Write(files, w, pecel.Stats{
+		FilesProcessed: stats.FilesProcessed,
+		Directories:    stats.Directories,
+		TotalBytes:     stats.TotalBytes,
+		TotalLines:     stats.TotalLines,
+		Duration:       stats.Duration,
+		Errors:         stats.Errors,
+	})
+}
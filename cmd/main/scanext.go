@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// noExtension labels files without an extension in the census.
+const noExtension = "(none)"
+
+// extensionCount totals the files with one extension.
+type extensionCount struct {
+	Ext   string
+	Files int
+	Bytes int64
+}
+
+// scanExtensions walks the input with every filter except -ext and totals
+// the files by lower-cased extension, largest total first. No content is
+// read.
+func scanExtensions(config Config, excludeRegex, includeRegex *regexp.Regexp) ([]extensionCount, error) {
+	config.Extensions = nil
+	var stats Stats
+	entries, err := collectFiles(context.Background(), config, excludeRegex, includeRegex, &stats)
+	if err != nil {
+		return nil, fmt.Errorf("walking directory: %w", err)
+	}
+
+	byExt := make(map[string]*extensionCount)
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Path))
+		if ext == "" {
+			ext = noExtension
+		}
+		count, ok := byExt[ext]
+		if !ok {
+			count = &extensionCount{Ext: ext}
+			byExt[ext] = count
+		}
+		count.Files++
+		count.Bytes += entry.Size
+	}
+
+	counts := make([]extensionCount, 0, len(byExt))
+	for _, count := range byExt {
+		counts = append(counts, *count)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Bytes != counts[j].Bytes {
+			return counts[i].Bytes > counts[j].Bytes
+		}
+		return counts[i].Ext < counts[j].Ext
+	})
+	return counts, nil
+}
+
+// printExtensions writes the -scan-extensions table.
+func printExtensions(w io.Writer, counts []extensionCount) {
+	width := len("total")
+	for _, count := range counts {
+		if len(count.Ext) > width {
+			width = len(count.Ext)
+		}
+	}
+
+	var files int
+	var bytes int64
+	for _, count := range counts {
+		fmt.Fprintf(w, "  %-*s  %6d  %10s\n", width, count.Ext, count.Files, formatBytes(count.Bytes))
+		files += count.Files
+		bytes += count.Bytes
+	}
+	fmt.Fprintf(w, "  %-*s  %6d  %10s\n", width, "total", files, formatBytes(bytes))
+}
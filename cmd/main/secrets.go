@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Actions taken on detected secrets (-secrets-action).
+const (
+	secretsWarn   = "warn"
+	secretsRedact = "redact"
+	secretsAbort  = "abort"
+)
+
+// secretRule is a pattern for one kind of credential. When group is set, only
+// that submatch is the secret, and it must also reach minEntropy bits per
+// character to count.
+type secretRule struct {
+	name       string
+	pattern    *regexp.Regexp
+	group      int
+	minEntropy float64
+}
+
+var secretRules = []secretRule{
+	{name: "aws-access-key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	// The whole block, so redaction removes the key body along with the header
+	{name: "private-key", pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----(?s:.*?)(?:-----END (?:[A-Z]+ )?PRIVATE KEY-----|\z)`)},
+	{name: "github-token", pattern: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{name: "slack-token", pattern: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{
+		name:       "high-entropy-token",
+		pattern:    regexp.MustCompile(`(?i)(?:api[_-]?key|secret|token|passw(?:or)?d|credential)[\w.-]*["']?\s*[:=]\s*["']?([A-Za-z0-9+/=_.-]{16,})`),
+		group:      1,
+		minEntropy: 3.5,
+	},
+}
+
+// secretFinding is one potential secret in a file.
+type secretFinding struct {
+	Path  string
+	Line  int
+	Rule  string
+	start int
+	end   int
+}
+
+// shannonEntropy returns the bits of entropy per character of s.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len(s))
+	entropy := 0.0
+	for _, c := range counts {
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// scanSecrets returns the potential secrets in info's content, in order of
+// position.
+func scanSecrets(info FileInfo) []secretFinding {
+	var findings []secretFinding
+	for _, rule := range secretRules {
+		for _, m := range rule.pattern.FindAllStringSubmatchIndex(info.Content, -1) {
+			start, end := m[0], m[1]
+			if rule.group > 0 {
+				start, end = m[2*rule.group], m[2*rule.group+1]
+				if shannonEntropy(info.Content[start:end]) < rule.minEntropy {
+					continue
+				}
+			}
+			findings = append(findings, secretFinding{
+				Path:  info.RelativePath,
+				Line:  strings.Count(info.Content[:start], "\n") + 1,
+				Rule:  rule.name,
+				start: start,
+				end:   end,
+			})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].start < findings[j].start
+	})
+	return findings
+}
+
+// redactSecrets replaces each finding in content with a placeholder naming
+// its rule. Overlapping findings are redacted once.
+func redactSecrets(content string, findings []secretFinding) string {
+	var b strings.Builder
+	last := 0
+	for _, f := range findings {
+		if f.start < last {
+			continue
+		}
+		b.WriteString(content[last:f.start])
+		b.WriteString("[REDACTED:" + f.Rule + "]")
+		last = f.end
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// checkSecrets scans every file for secrets, reports each finding with its
+// file and line, and applies config.SecretsAction: redact rewrites the
+// content in place, abort returns an error so nothing is written.
+func checkSecrets(fileInfos []FileInfo, config Config) error {
+	total := 0
+	for i := range fileInfos {
+		info := &fileInfos[i]
+		findings := scanSecrets(*info)
+		if len(findings) == 0 {
+			continue
+		}
+		total += len(findings)
+		for _, f := range findings {
+			fmt.Printf("%s Potential secret (%s) at %s:%d\n", yellow("⚠"), f.Rule, f.Path, f.Line)
+		}
+		if config.SecretsAction == secretsRedact {
+			info.Content = redactSecrets(info.Content, findings)
+		}
+	}
+
+	if total > 0 && config.SecretsAction == secretsAbort {
+		return fmt.Errorf("found %d potential secrets; nothing was written (-secrets-action abort)", total)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// remoteInputURL parses an -input of the form sftp://[user@]host[:port]/path.
+func remoteInputURL(input string) (*url.URL, bool) {
+	u, err := url.Parse(input)
+	if err != nil || u.Scheme != "sftp" || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+// openRemoteInput connects to an sftp:// input and returns its tree as an
+// inputFS together with the absolute remote path of its root, which becomes
+// InputDir. The walk, filters and transforms then run on the remote files
+// directly, so only the files that pass the filters are ever downloaded.
+func openRemoteInput(u *url.URL) (inputFS, string, error) {
+	conn, client, err := dialSFTP(u)
+	if err != nil {
+		return nil, "", fmt.Errorf("connecting to %s: %w", u.Redacted(), err)
+	}
+	fsys := &sftpFS{conn: conn, client: client}
+
+	root := u.Path
+	if root == "" {
+		root = "."
+	}
+	if root, err = client.RealPath(root); err != nil {
+		fsys.Close()
+		return nil, "", fmt.Errorf("resolving %s: %w", u.Redacted(), err)
+	}
+	info, err := client.Stat(root)
+	if err != nil {
+		fsys.Close()
+		return nil, "", fmt.Errorf("reading %s: %w", u.Redacted(), err)
+	}
+	if !info.IsDir() {
+		fsys.Close()
+		return nil, "", fmt.Errorf("%s is not a directory", u.Redacted())
+	}
+	fsys.root = root
+	return fsys, filepath.FromSlash(root), nil
+}
+
+// dialSFTP opens an SSH connection to u and starts its sftp subsystem. Keys
+// come from the SSH agent (SSH_AUTH_SOCK) and the unencrypted default keys in
+// ~/.ssh; the host key must be listed in ~/.ssh/known_hosts.
+func dialSFTP(u *url.URL) (*ssh.Client, *sftp.Client, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	hostKeys, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading known hosts: %w", err)
+	}
+
+	var signers []ssh.Signer
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			defer conn.Close()
+			if agentSigners, err := agent.NewClient(conn).Signers(); err == nil {
+				signers = append(signers, agentSigners...)
+			}
+		}
+	}
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		key, err := os.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		if signer, err := ssh.ParsePrivateKey(key); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) == 0 {
+		return nil, nil, errors.New("no SSH agent or usable key in ~/.ssh")
+	}
+
+	user := u.User.Username()
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "22")
+	}
+	conn, err := ssh.Dial("tcp", host, &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+		HostKeyCallback: hostKeys,
+		Timeout:         30 * time.Second,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, client, nil
+}
+
+// sftpFS serves the tree under root on an SFTP server as an inputFS. Files
+// are opened as *sftp.File, whose WriteTo reads with several requests in
+// flight.
+type sftpFS struct {
+	conn   *ssh.Client
+	client *sftp.Client
+	root   string
+}
+
+// remote returns the server path of name, a slash-separated path below root.
+func (f *sftpFS) remote(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(f.root, name), nil
+}
+
+func (f *sftpFS) Open(name string) (fs.File, error) {
+	p, err := f.remote("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.client.Open(p)
+}
+
+func (f *sftpFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := f.remote("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.client.Stat(p)
+}
+
+func (f *sftpFS) Lstat(name string) (fs.FileInfo, error) {
+	p, err := f.remote("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.client.Lstat(p)
+}
+
+func (f *sftpFS) ReadLink(name string) (string, error) {
+	p, err := f.remote("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	return f.client.ReadLink(p)
+}
+
+// ReadDir lists a directory sorted by name, as fs.ReadDirFS requires; the
+// entries carry the attributes the listing returned, so symlinks are not
+// followed.
+func (f *sftpFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := f.remote("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := f.client.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+	return entries, nil
+}
+
+// Close ends the sftp session and the SSH connection under it.
+func (f *sftpFS) Close() error {
+	err := f.client.Close()
+	if f.conn != nil {
+		if cerr := f.conn.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
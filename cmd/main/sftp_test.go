@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// newTestSFTPFS serves files from an in-memory SFTP server over pipes and
+// returns the tree under root as an sftpFS.
+func newTestSFTPFS(t *testing.T, root string, files map[string]string) *sftpFS {
+	t.Helper()
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	server := sftp.NewRequestServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverRead, serverWrite}, sftp.InMemHandler())
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientRead, clientWrite)
+	if err != nil {
+		t.Fatalf("sftp.NewClientPipe: %v", err)
+	}
+	for name, content := range files {
+		p := path.Join(root, name)
+		if err := client.MkdirAll(path.Dir(p)); err != nil {
+			t.Fatal(err)
+		}
+		f, err := client.Create(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	fsys := &sftpFS{client: client, root: root}
+	// Closing the server ends the client's read loop, which Close waits for
+	t.Cleanup(func() {
+		server.Close()
+		fsys.Close()
+	})
+	return fsys
+}
+
+// countingFS records the files opened through it.
+type countingFS struct {
+	inputFS
+	mu     sync.Mutex
+	opened map[string]int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.mu.Lock()
+	c.opened[name]++
+	c.mu.Unlock()
+	return c.inputFS.Open(name)
+}
+
+// TestRemoteInputReadsOnlySelectedFiles walks an SFTP tree in place and
+// checks that the filters run before any file is downloaded.
+func TestRemoteInputReadsOnlySelectedFiles(t *testing.T) {
+	files := map[string]string{
+		"a.go":           "package a\n",
+		"notes.txt":      "not a go file\n",
+		"sub/b.go":       "package b\n",
+		"sub/skip/c.go":  "package c\n",
+		".hidden/d.go":   "package d\n",
+		".gitignore":     "skip/\n",
+		"sub/.gitignore": "",
+	}
+	for _, walkers := range []int{1, 4} {
+		fsys := &countingFS{inputFS: newTestSFTPFS(t, "/src", files), opened: make(map[string]int)}
+
+		config := defaultConfig()
+		config.InputDir = filepath.FromSlash("/src")
+		config.inputFS = fsys
+		config.Quiet = true
+		config.Hash = true
+		config.GitIgnore = true
+		config.Extensions = []string{".go"}
+		config.WalkParallel = walkers
+		config.Parallel = 2
+		config.gitignores = newGitignoreCache(config.InputDir, inputOpener(config), false)
+
+		var stats Stats
+		entries, err := collectFiles(context.Background(), config, nil, nil, &stats)
+		if err != nil {
+			t.Fatalf("collectFiles: %v", err)
+		}
+		infos := processFilesParallel(context.Background(), entries, config, &stats)
+
+		var got []string
+		for _, info := range infos {
+			got = append(got, info.RelativePath+": "+info.Content)
+			if info.Hash == "" {
+				t.Errorf("%s has no hash", info.RelativePath)
+			}
+		}
+		want := []string{"a.go: package a\n", "sub/b.go: package b\n"}
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("walk-parallel %d: got %q, want %q", walkers, got, want)
+		}
+		if stats.Errors != 0 {
+			t.Errorf("walk-parallel %d: %d errors", walkers, stats.Errors)
+		}
+		for name := range fsys.opened {
+			switch name {
+			case "a.go", "sub/b.go", ".gitignore", "sub/.gitignore":
+			default:
+				t.Errorf("walk-parallel %d: opened %s, which the filters exclude", walkers, name)
+			}
+		}
+	}
+}
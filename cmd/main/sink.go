@@ -0,0 +1,300 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// outputSinks open remote -output destinations, keyed on URL scheme. Any
+// other output is a local path.
+var outputSinks = map[string]func(*url.URL) (io.WriteCloser, error){
+	"http":  openHTTPSink,
+	"https": openHTTPSink,
+	"s3":    openS3Sink,
+}
+
+// stdoutOutput is the -output value that writes the bundle to standard
+// output; every message then goes to stderr.
+const stdoutOutput = "-"
+
+// bundleStdout is the real standard output, kept for the bundle once
+// os.Stdout has been pointed at stderr.
+var bundleStdout io.Writer = os.Stdout
+
+// stdoutSink writes to standard output and leaves it open on Close.
+type stdoutSink struct {
+	io.Writer
+}
+
+func (stdoutSink) Close() error { return nil }
+
+// remoteOutputURL parses path as a URL with a scheme in outputSinks.
+func remoteOutputURL(path string) (*url.URL, bool) {
+	u, err := url.Parse(path)
+	if err != nil || outputSinks[u.Scheme] == nil || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+// isRemoteOutput reports whether path names a remote sink.
+func isRemoteOutput(path string) bool {
+	_, ok := remoteOutputURL(path)
+	return ok
+}
+
+// openOutput opens the destination for a rendered output: a remote sink for
+// a URL, otherwise a new local file. Close reports whether the output was
+// stored, so its error must be checked.
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == stdoutOutput {
+		return stdoutSink{bundleStdout}, nil
+	}
+	if u, ok := remoteOutputURL(path); ok {
+		return outputSinks[u.Scheme](u)
+	}
+	return os.Create(path)
+}
+
+// abortOutput closes an output that failed part way. Remote sinks drop the
+// partial upload instead of storing it.
+func abortOutput(output io.WriteCloser, err error) {
+	if sink, ok := output.(interface{ abort(error) }); ok {
+		sink.abort(err)
+		return
+	}
+	output.Close()
+}
+
+// httpSink streams the output as the body of a PUT request.
+type httpSink struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+// openHTTPSink starts a PUT to u whose body is everything written to the
+// sink. Credentials in the URL are sent as basic auth; PECEL_OUTPUT_TOKEN,
+// when set, is sent as a bearer token.
+func openHTTPSink(u *url.URL) (io.WriteCloser, error) {
+	pr, pw := io.Pipe()
+	req, err := http.NewRequest(http.MethodPut, u.String(), pr)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("PECEL_OUTPUT_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	sink := &httpSink{pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := doUpload(req)
+		// Fail pending writes instead of leaving them blocked on the pipe
+		pr.CloseWithError(err)
+		sink.done <- err
+	}()
+	return sink, nil
+}
+
+func (s *httpSink) Write(p []byte) (int, error) {
+	return s.pw.Write(p)
+}
+
+// Close ends the request body and waits for the server's answer.
+func (s *httpSink) Close() error {
+	s.pw.Close()
+	return <-s.done
+}
+
+// abort fails the request so the server does not store a partial body.
+func (s *httpSink) abort(err error) {
+	s.pw.CloseWithError(err)
+	<-s.done
+}
+
+// doUpload sends req and turns a non-2xx response into an error.
+func doUpload(req *http.Request) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("upload to %s failed: %s %s", req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// s3MaxPutSize is the largest object S3 accepts in a single PUT. Larger
+// outputs would need a multipart upload, which the sink does not do.
+const s3MaxPutSize = 5 << 30
+
+// s3Sink spools the output to a temporary file, since S3 needs the length of
+// an object up front, and uploads it on Close with a single PUT, so outputs
+// are limited to s3MaxPutSize.
+type s3Sink struct {
+	*os.File
+	bucket, key string
+}
+
+// openS3Sink prepares an upload to s3://bucket/key. Credentials and region
+// come from the standard AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN and AWS_REGION (or AWS_DEFAULT_REGION) variables;
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL points at an S3-compatible store.
+func openS3Sink(u *url.URL) (io.WriteCloser, error) {
+	key := strings.TrimPrefix(u.Path, "/")
+	if key == "" {
+		return nil, fmt.Errorf("%s has no object key", u)
+	}
+	if os.Getenv("AWS_ACCESS_KEY_ID") == "" || os.Getenv("AWS_SECRET_ACCESS_KEY") == "" {
+		return nil, fmt.Errorf("uploading to %s needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", u)
+	}
+	file, err := os.CreateTemp("", "pecel-s3-*")
+	if err != nil {
+		return nil, err
+	}
+	return &s3Sink{File: file, bucket: u.Host, key: key}, nil
+}
+
+// abort discards the spooled output without uploading it.
+func (s *s3Sink) abort(error) {
+	s.File.Close()
+	os.Remove(s.File.Name())
+}
+
+// Close uploads the spooled output and removes the temporary file.
+func (s *s3Sink) Close() error {
+	defer os.Remove(s.File.Name())
+	size, err := s.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		s.File.Close()
+		return err
+	}
+	if _, err := s.File.Seek(0, io.SeekStart); err != nil {
+		s.File.Close()
+		return err
+	}
+	defer s.File.Close()
+	if size > s3MaxPutSize {
+		return fmt.Errorf("%s is too large for a single S3 upload (the limit is %s); use -split-size to write parts",
+			formatBytes(size), formatBytes(s3MaxPutSize))
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	// Custom endpoints use path-style addressing, AWS itself virtual hosts
+	var target string
+	escapedKey := awsURIEscape(s.key)
+	if endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); endpoint != "" {
+		target = strings.TrimSuffix(endpoint, "/") + "/" + awsURIEscape(s.bucket) + "/" + escapedKey
+	} else {
+		target = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, region, escapedKey)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, target, io.NopCloser(s.File))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	signS3Request(req, region, time.Now().UTC())
+	return doUpload(req)
+}
+
+// firstEnv returns the value of the first of names that is set.
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// signS3Request adds AWS Signature Version 4 headers to req. Every header
+// already on req is signed along with the host. The payload is the one
+// named by an x-amz-content-sha256 header, or left unsigned so the body can
+// be streamed from disk.
+func signS3Request(req *http.Request, region string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	if req.Header.Get("x-amz-content-sha256") == "" {
+		req.Header.Set("x-amz-content-sha256", "UNSIGNED-PAYLOAD")
+	}
+	req.Header.Set("x-amz-date", amzDate)
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("x-amz-security-token", token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsURIEscape(req.URL.Path),
+		"", // no query string
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("x-amz-content-sha256"),
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		os.Getenv("AWS_ACCESS_KEY_ID"), scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEscape percent-encodes s as SigV4 requires: everything but unreserved
+// characters, keeping "/" so keys can contain directories.
+func awsURIEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
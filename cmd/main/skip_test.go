@@ -0,0 +1,41 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFileSkipReasonSniffsBinariesOnce checks that the large-binary and
+// binary filters share one read of the file.
+func TestFileSkipReasonSniffsBinariesOnce(t *testing.T) {
+	files := map[string]string{
+		"large.bin": strings.Repeat("\x00\x01", 64),
+		"small.bin": "\x00\x01",
+		"text.txt":  strings.Repeat("text\n", 64),
+	}
+	fsys := &countingFS{inputFS: newTestSFTPFS(t, "/src", files), opened: make(map[string]int)}
+
+	config := defaultConfig()
+	config.InputDir = filepath.FromSlash("/src")
+	config.inputFS = fsys
+	config.LargeBinaryThreshold = 16
+
+	for name, want := range map[string]skipReason{
+		"large.bin": skipLargeBinary,
+		"small.bin": skipBinary,
+		"text.txt":  skipNone,
+	} {
+		path := filepath.Join(config.InputDir, name)
+		info, err := lstatInput(path, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fileSkipReason(path, info, config, nil, nil); got != want {
+			t.Errorf("fileSkipReason(%s) = %v, want %v", name, got, want)
+		}
+		if n := fsys.opened[name]; n != 1 {
+			t.Errorf("%s was opened %d times, want once", name, n)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema is the table -format sqlite fills, one row per file.
+const sqliteSchema = `
+CREATE TABLE files (
+	path           TEXT NOT NULL,
+	relative_path  TEXT NOT NULL PRIMARY KEY,
+	size           INTEGER NOT NULL,
+	modified       TEXT NOT NULL,
+	hash           TEXT,
+	content_type   TEXT,
+	diff_status    TEXT,
+	link_target    TEXT,
+	content        TEXT
+);
+CREATE INDEX files_hash ON files (hash);
+`
+
+// nullIfEmpty stores empty optional fields as NULL.
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// writeSQLiteOutput writes fileInfos to a new SQLite database at
+// config.OutputFile, replacing any existing file, and returns its size. The
+// driver is pure Go, so the format also works in builds without cgo.
+func writeSQLiteOutput(fileInfos []FileInfo, config Config) (int64, error) {
+	if err := os.Remove(config.OutputFile); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	db, err := sql.Open("sqlite", config.OutputFile)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO files
+		(path, relative_path, size, modified, hash, content_type, diff_status, link_target, content)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, info := range fileInfos {
+		content := sql.NullString{String: info.Content, Valid: !info.ContentOmitted && info.LinkTarget == ""}
+		if _, err := stmt.Exec(info.Path, info.RelativePath, info.Size, info.Modified,
+			nullIfEmpty(info.Hash), nullIfEmpty(info.ContentType), nullIfEmpty(info.DiffStatus),
+			nullIfEmpty(info.LinkTarget), content); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	if err := db.Close(); err != nil {
+		return 0, err
+	}
+
+	stat, err := os.Stat(config.OutputFile)
+	if err != nil {
+		return 0, err
+	}
+	return stat.Size(), nil
+}
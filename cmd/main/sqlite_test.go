@@ -0,0 +1,51 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteSQLiteOutput writes a database and reads the rows back through
+// the same pure-Go driver.
+func TestWriteSQLiteOutput(t *testing.T) {
+	config := defaultConfig()
+	config.OutputFile = filepath.Join(t.TempDir(), "out.db")
+	files := []FileInfo{
+		{Path: "/src/a.go", RelativePath: "a.go", Size: 10, Modified: "2024-01-02 03:04:05", Content: "package a\n", Hash: "abc"},
+		{Path: "/src/b.txt", RelativePath: "b.txt", Size: 3, Modified: "2024-01-02 03:04:05", Content: "hi\n"},
+	}
+	if _, err := writeSQLiteOutput(files, config); err != nil {
+		t.Fatalf("writeSQLiteOutput: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", config.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT relative_path, size, hash, content FROM files ORDER BY relative_path`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []FileInfo
+	for rows.Next() {
+		var info FileInfo
+		var hash sql.NullString
+		if err := rows.Scan(&info.RelativePath, &info.Size, &hash, &info.Content); err != nil {
+			t.Fatal(err)
+		}
+		info.Hash = hash.String
+		got = append(got, info)
+	}
+	if len(got) != len(files) {
+		t.Fatalf("got %d rows, want %d", len(got), len(files))
+	}
+	for i, want := range files {
+		if got[i].RelativePath != want.RelativePath || got[i].Size != want.Size ||
+			got[i].Hash != want.Hash || got[i].Content != want.Content {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], want)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// streamJSONLines processes entries and writes each file to w as one line of
+// JSON as soon as it is ready, flushing after every line so a consumer can
+// start work before the walk is done. Lines come in completion order, not
+// walk order.
+//
+// Only the files in flight are held in memory. Workers hand their results to
+// the writer over an unbuffered channel, so when the consumer reads slowly
+// the writes block, the workers block behind them, and the run slows to the
+// consumer's pace rather than buffering the export.
+func streamJSONLines(ctx context.Context, entries []walkEntry, w io.Writer, config Config, stats *Stats) error {
+	workers := config.Parallel
+	if workers < 1 {
+		workers = 1
+	}
+
+	feed := make(chan walkEntry)
+	results := make(chan FileInfo)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var vanished, tooLong, failed int32
+	config.console = startConsole()
+	bar := startProgressBar(len(entries), config)
+
+	go func() {
+		defer close(feed)
+		for _, i := range readOrder(entries, config.ReadOrder) {
+			select {
+			case feed <- entries[i]:
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range feed {
+				info, err := processSingleFile(entry.Path, config)
+				config.progress.fileDone(info.Size)
+				bar.add()
+				if errors.Is(err, errNoContentMatch) {
+					continue
+				}
+				if isVanished(entry.Path, err, config) {
+					atomic.AddInt32(&vanished, 1)
+					reportVanished(entry.Path, config)
+					continue
+				}
+				if isPathTooLong(err) {
+					atomic.AddInt32(&tooLong, 1)
+					reportPathTooLong(entry.Path, config)
+					continue
+				}
+				if err != nil {
+					atomic.AddInt32(&failed, 1)
+					if !config.Quiet {
+						config.console.printf("%s Error processing %s: %v\n", red("✗"), entry.Path, err)
+					}
+					continue
+				}
+				reportWalkDrift(entry, info, config)
+				select {
+				case results <- info:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	bufWriter := bufio.NewWriter(w)
+	var streamErr error
+	for info := range results {
+		if streamErr != nil {
+			continue
+		}
+		streamErr = streamJSONLine(info, bufWriter, config, stats)
+		if streamErr != nil {
+			close(done)
+		}
+	}
+	config.console.close()
+	stats.Vanished += int(vanished)
+	stats.PathTooLong += int(tooLong)
+	stats.Errors += int(failed)
+	return streamErr
+}
+
+// streamJSONLine checks one file for secrets, then writes and flushes it.
+func streamJSONLine(info FileInfo, w *bufio.Writer, config Config, stats *Stats) error {
+	if config.DetectSecrets {
+		single := []FileInfo{info}
+		if err := checkSecrets(single, config); err != nil {
+			return err
+		}
+		info = single[0]
+	}
+
+	if config.NumberFiles {
+		info.Index = stats.FilesProcessed + 1
+	}
+	line, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	w.Write(line)
+	w.WriteByte('\n')
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	stats.FilesProcessed++
+	stats.TotalBytes += info.Size
+	stats.TotalLines += info.LineCount
+	if config.GroupSummary {
+		stats.Groups = addToGroups(stats.Groups, info, config)
+	}
+	return nil
+}
+
+// runStream is run for -output-json-streaming-to-stdout: it streams the files
+// to config.jsonStream instead of rendering an output file, then prints the
+// summary.
+func runStream(ctx context.Context, config Config, excludeRegex, includeRegex *regexp.Regexp, startTime time.Time) (err error) {
+	var stats Stats
+	defer func() { recordMetrics(config, &stats, startTime, err) }()
+	counter := &countingWriter{w: config.jsonStream}
+
+	if config.InputDir == stdinInput {
+		info, err := readStdinInput(config)
+		if err != nil {
+			return fmt.Errorf("reading stdin: %w", err)
+		}
+		if err := streamJSONLine(info, bufio.NewWriter(counter), config, &stats); err != nil {
+			return fmt.Errorf("streaming output: %w", err)
+		}
+	} else {
+		phaseStart := time.Now()
+		entries, err := collectFiles(ctx, config, excludeRegex, includeRegex, &stats)
+		if err != nil {
+			return fmt.Errorf("walking directory: %w", err)
+		}
+		stats.WalkDuration = time.Since(phaseStart).Seconds()
+		if !config.Quiet {
+			fmt.Printf("%s Found %d files to process\n", cyan("→"), len(entries))
+		}
+
+		// Reading and writing overlap, so they are reported as one phase
+		phaseStart = time.Now()
+		config.progress.begin(len(entries))
+		err = streamJSONLines(ctx, entries, counter, config, &stats)
+		config.progress.finish()
+		stats.ProcessDuration = time.Since(phaseStart).Seconds()
+		if err != nil {
+			return fmt.Errorf("streaming output: %w", err)
+		}
+	}
+
+	stats.Duration = time.Since(startTime).Seconds()
+	stats.OutputSize = counter.n
+	stats.UncompressedSize = counter.n
+	sortGroups(stats.Groups)
+	printSummary(stats, config)
+	if config.GroupSummary {
+		printGroups(stats.Groups, stats.TotalBytes)
+	}
+	if config.QuarantineOversize {
+		printOversize(stats.Oversize, config.MaxFileSize)
+	}
+	if ctx.Err() != nil {
+		return reportCanceled(ctx, stats)
+	}
+	fmt.Printf("\n%s Processing completed successfully!\n", green("✓"))
+	return nil
+}
+
+// canStreamOutput reports whether run can write the output while files are
+// still being read, keeping only their metadata. Options that need the whole
+// bundle before the first file is written, or that report on it afterwards,
+// keep the buffered path.
+func canStreamOutput(config Config) bool {
+	format := strings.ToLower(config.OutputFormat)
+	switch format {
+	case "text", "json", "xml", "markdown", "md":
+	default:
+		return false
+	}
+	return !config.DryRun && !config.Watch && !config.ContentHashOnly && !config.NumberFiles && !config.MarkdownTOC &&
+		!config.DetectSecrets && !config.ReportDuplicates && config.DiffAgainst == "" &&
+		config.contentRegex == nil && config.fileOrder == nil && config.ExternalizeContent == "" &&
+		config.OutputDirMirror == "" && !splitsOutput(config) &&
+		(config.ReadOrder == "" || config.ReadOrder == readOrderDirectory)
+}
+
+// writeStreamedOutput reads entries on -parallel workers and writes each file
+// to the output once it and every file before it in the walk are ready, so
+// the output keeps walk order while only the files in flight hold content.
+// Files are dispatched a window of consecutive walk entries at a time, each
+// window in -read-order, so reading can be reordered without holding more
+// than two windows of content. A window covers streamWindowBytes of files,
+// so the largest files of a directory are usually dispatched first, but
+// not ones a window apart. header is what the document header shows,
+// since the processed totals are not known until the end. It returns the
+// files without their content.
+func writeStreamedOutput(ctx context.Context, entries []walkEntry, config Config, stats *Stats, header Stats) ([]FileInfo, int64, int64, error) {
+	workers := config.Parallel
+	if workers < 1 {
+		workers = 1
+	}
+	type result struct {
+		info FileInfo
+		err  error
+	}
+	type job struct {
+		entry walkEntry
+		slot  chan result
+	}
+
+	// Each entry gets a result slot, queued in walk order a window at a
+	// time; one window waits in the queue while the writer finishes the
+	// previous one, so reading never runs further ahead of the writer
+	type window struct {
+		entries []walkEntry
+		slots   []chan result
+	}
+	jobs := make(chan job, workers)
+	windows := make(chan window, 1)
+	config.console = startConsole()
+	bar := startProgressBar(len(entries), config)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				info, err := processSingleFile(j.entry.Path, config)
+				config.progress.fileDone(info.Size)
+				bar.add()
+				if err == nil {
+					reportWalkDrift(j.entry, info, config)
+				}
+				j.slot <- result{info: info, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(windows)
+		defer close(jobs)
+		for start := 0; start < len(entries); {
+			// Once canceled, the document is closed after the files
+			// already queued
+			if ctx.Err() != nil {
+				return
+			}
+			end := streamWindowEnd(entries, start, workers*2)
+			w := window{entries: entries[start:end], slots: make([]chan result, end-start)}
+			for i := range w.slots {
+				w.slots[i] = make(chan result, 1)
+			}
+			windows <- w
+			for _, i := range readOrder(w.entries, config.ReadOrder) {
+				jobs <- job{entry: w.entries[i], slot: w.slots[i]}
+			}
+			start = end
+		}
+	}()
+
+	files := make(chan FileInfo)
+	type written struct {
+		size, rendered int64
+		err            error
+	}
+	done := make(chan written, 1)
+	go func() {
+		size, rendered, err := writeOutputFile(config, header, func(w io.Writer, config Config) error {
+			return writeFormatStream(files, w, config, header)
+		})
+		done <- written{size: size, rendered: rendered, err: err}
+		// The output may have failed before rendering started
+		drainFiles(files)
+	}()
+
+	var fileInfos []FileInfo
+	for w := range windows {
+		for i, slot := range w.slots {
+			r := <-slot
+			path := w.entries[i].Path
+			switch {
+			case errors.Is(r.err, errNoContentMatch):
+				continue
+			case isVanished(path, r.err, config):
+				stats.Vanished++
+				reportVanished(path, config)
+				continue
+			case isPathTooLong(r.err):
+				stats.PathTooLong++
+				reportPathTooLong(path, config)
+				continue
+			case r.err != nil:
+				stats.Errors++
+				if !config.Quiet {
+					config.console.printf("%s Error processing %s: %v\n", red("✗"), path, r.err)
+				}
+				continue
+			}
+			files <- r.info
+			stats.FilesProcessed++
+			stats.TotalBytes += r.info.Size
+			stats.TotalLines += r.info.LineCount
+			r.info.Content = ""
+			fileInfos = append(fileInfos, r.info)
+		}
+	}
+	close(files)
+	wg.Wait()
+	config.console.close()
+
+	out := <-done
+	return fileInfos, out.size, out.rendered, out.err
+}
+
+// streamWindowBytes is about how much file content one dispatch window of
+// writeStreamedOutput covers.
+const streamWindowBytes = 32 << 20
+
+// streamWindowEnd returns the end of the dispatch window of entries that
+// starts at start: at least minEntries of them, then as many more as fit in
+// streamWindowBytes.
+func streamWindowEnd(entries []walkEntry, start, minEntries int) int {
+	end, size := start, int64(0)
+	for end < len(entries) && (end-start < minEntries || size+entries[end].Size <= streamWindowBytes) {
+		size += entries[end].Size
+		end++
+	}
+	return end
+}
+
+// writeFormatStream renders files in one of the formats canStreamOutput
+// accepts.
+func writeFormatStream(files <-chan FileInfo, w io.Writer, config Config, stats Stats) error {
+	var err error
+	switch strings.ToLower(config.OutputFormat) {
+	case "json":
+		_, err = writeJSONStream(files, w, config, stats)
+	case "xml":
+		_, err = writeXMLStream(files, w, config, stats)
+	case "markdown", "md":
+		_, err = writeMarkdownStream(files, w, config, stats)
+	default:
+		_, err = writeTextStream(files, w, config, stats)
+	}
+	return err
+}
+
+// walkedBytes is the total size the walk recorded for entries.
+func walkedBytes(entries []walkEntry) int64 {
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+	return total
+}
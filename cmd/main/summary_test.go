@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSummarySizeRow checks that the summary only shows a ratio when a codec
+// compressed the output.
+func TestSummarySizeRow(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": strings.Repeat("pecel\n", 1000)})
+
+	for _, tc := range []struct {
+		args      []string
+		want, not string
+	}{
+		{nil, "Output size: ", "ratio"},
+		{[]string{"-compression", "gzip"}, "Compression ratio: ", "Output/input"},
+	} {
+		args := append([]string{"-input", dir, "-output", filepath.Join(t.TempDir(), "out.txt"), "-plain-summary"}, tc.args...)
+		out, err := pecelCommand(args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("pecel %v: %v\n%s", tc.args, err, out)
+		}
+		if !strings.Contains(string(out), tc.want) || strings.Contains(string(out), tc.not) {
+			t.Errorf("pecel %v summary should show %q and not %q:\n%s", tc.args, tc.want, tc.not, out)
+		}
+	}
+}
+
+// TestTextFooterContentSize checks that the text footer labels the size it
+// knows, the document before the footer, as the content size.
+func TestTextFooterContentSize(t *testing.T) {
+	var buf bytes.Buffer
+	files := []FileInfo{{RelativePath: "a.txt", Content: "pecel"}}
+	n, err := writeTextOutput(files, &buf, defaultConfig(), Stats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	footer := out[strings.Index(out, "\n\n=== SUMMARY ===\n"):]
+	want := fmt.Sprintf("Content size: %s\n", formatBytes(n-int64(len(footer))))
+	if !strings.Contains(footer, want) || strings.Contains(footer, "Output size") {
+		t.Errorf("footer should hold %q:\n%s", want, footer)
+	}
+}
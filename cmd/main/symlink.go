@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultMaxSymlinkDepth caps symlink hops along one path when
+// -max-symlink-depth is not set.
+const defaultMaxSymlinkDepth = 8
+
+// maxSymlinkDepth returns the configured hop limit, or the default.
+func maxSymlinkDepth(config Config) int {
+	if config.MaxSymlinkDepth > 0 {
+		return config.MaxSymlinkDepth
+	}
+	return defaultMaxSymlinkDepth
+}
+
+// symlinkHops counts the links in the chain starting at path, stopping once
+// limit is exceeded so a loop of links terminates.
+func symlinkHops(path string, limit int, config Config) (int, error) {
+	hops := 0
+	for hops <= limit {
+		info, err := lstatInput(path, config)
+		if err != nil {
+			return hops, err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return hops, nil
+		}
+		target, err := readInputLink(path, config)
+		if err != nil {
+			return hops, err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+		hops++
+	}
+	return hops, nil
+}
+
+// followSymlinkDir decides whether the walk may enter the directory the link
+// at path points to, having already followed hops links to get here. It
+// refuses links that would exceed -max-symlink-depth and links back into a
+// directory already on the walk (a cycle), logging why. On success it records
+// the target as followed and returns the hops the link itself adds.
+func followSymlinkDir(path string, hops int, followed map[string]bool, config Config) (int, bool) {
+	limit := maxSymlinkDepth(config)
+	linkHops, err := symlinkHops(path, limit, config)
+	if err != nil {
+		return 0, false
+	}
+	if hops+linkHops > limit {
+		if !config.Quiet {
+			fmt.Printf("%s Not following %s: more than %d symlink hops (-max-symlink-depth)\n",
+				yellow("⚠"), path, limit)
+		}
+		return 0, false
+	}
+
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return 0, false
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return 0, false
+	}
+	if isWithin(parent, target) {
+		if config.Verbose && !config.Quiet {
+			fmt.Printf("%s Not following %s: symlink cycle back to %s\n", cyan("↳"), path, target)
+		}
+		return 0, false
+	}
+	// A materialized link may copy a directory walked elsewhere, as long as it
+	// does not lead back into the path that reached it
+	if config.MaterializeSymlinks {
+		if onWalkPath(config.InputDir, path, target) {
+			if config.Verbose && !config.Quiet {
+				fmt.Printf("%s Not following %s: symlink cycle back to %s\n", cyan("↳"), path, target)
+			}
+			return 0, false
+		}
+		return linkHops, true
+	}
+	if followed[target] {
+		if config.Verbose && !config.Quiet {
+			fmt.Printf("%s Not following %s: %s was already walked\n", cyan("↳"), path, target)
+		}
+		return 0, false
+	}
+	followed[target] = true
+	return linkHops, true
+}
+
+// onWalkPath reports whether target is the resolved form of any directory the
+// walk passed through between root and path, including links already followed
+// along the way.
+func onWalkPath(root, path, target string) bool {
+	dir := filepath.Dir(path)
+	for {
+		if real, err := filepath.EvalSymlinks(dir); err == nil && real == target {
+			return true
+		}
+		parent := filepath.Dir(dir)
+		if dir == root || parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// isWithin reports whether path is dir or lies below it.
+func isWithin(path, dir string) bool {
+	if path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// testFileNames matches the file names of test sources in common ecosystems.
+var testFileNames = []*regexp.Regexp{
+	regexp.MustCompile(`_test\.go$`),                               // Go
+	regexp.MustCompile(`\.(test|spec)\.[cm]?[jt]sx?$`),             // JavaScript, TypeScript
+	regexp.MustCompile(`^test_.*\.py$|_test\.py$`),                 // Python (pytest)
+	regexp.MustCompile(`(Test|Tests|IT)\.(java|kt|scala|groovy)$`), // JVM (JUnit)
+	regexp.MustCompile(`(Test|Tests)\.(cs|php|swift)$`),            // C#, PHP, Swift (XCTest)
+	regexp.MustCompile(`_(spec|test)\.rb$`),                        // Ruby (RSpec, minitest)
+	regexp.MustCompile(`_test\.(exs|dart|c|cc|cpp)$|_spec\.lua$`),  // Elixir, Dart, C/C++, Lua (busted)
+}
+
+// testDirs are directories holding only tests by convention.
+var testDirs = map[string]bool{
+	"__tests__": true, // Jest
+}
+
+// isTestFile reports whether relPath looks like a test source file.
+func isTestFile(relPath string) bool {
+	name := filepath.Base(relPath)
+	for _, re := range testFileNames {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	for _, dir := range strings.Split(filepath.Dir(relPath), string(filepath.Separator)) {
+		if testDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
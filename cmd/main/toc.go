@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// headingSlug turns a markdown heading into its anchor the way GitHub does:
+// lower-cased, spaces turned into hyphens, and punctuation other than
+// hyphens and underscores dropped.
+func headingSlug(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// slugger hands out unique anchors for the headings of one document,
+// suffixing repeats with -1, -2 and so on as GitHub does.
+type slugger map[string]int
+
+func (s slugger) slug(heading string) string {
+	base := headingSlug(heading)
+	slug := base
+	if n, seen := s[base]; seen {
+		for {
+			n++
+			slug = fmt.Sprintf("%s-%d", base, n)
+			if _, taken := s[slug]; !taken {
+				break
+			}
+		}
+		s[base] = n
+	}
+	s[slug] = 0
+	return slug
+}
+
+// markdownTOC renders the -markdown-toc table of contents for the markdown
+// document writeMarkdownStream writes for fileInfos. Anchors are assigned in
+// document order, including the "Content" headings between the file
+// headings, so repeated headings get the same suffixes GitHub gives them.
+// Files collapsed by -compact-empty-sections have no heading and are listed
+// without a link.
+func markdownTOC(fileInfos []FileInfo, config Config) string {
+	slugs := slugger{}
+	if !config.NoHeader {
+		slugs.slug("Pecel Output")
+	}
+	var b strings.Builder
+	b.WriteString("## " + markdownTOCTitle + "\n\n")
+	slugs.slug(markdownTOCTitle)
+	for i, info := range fileInfos {
+		label := markdownFileLabel(info, i, config)
+		if isCompactSection(info, config) {
+			fmt.Fprintf(&b, "- %s: `%s` (empty)\n", label, info.RelativePath)
+			continue
+		}
+		anchor := slugs.slug(fmt.Sprintf("%s: `%s`", label, info.RelativePath))
+		fmt.Fprintf(&b, "- [%s: `%s`](#%s)\n", label, info.RelativePath, anchor)
+		if !info.ContentOmitted {
+			slugs.slug("Content")
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// markdownTOCTitle is the heading of the table of contents.
+const markdownTOCTitle = "Table of Contents"
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHeadingSlug(t *testing.T) {
+	for heading, want := range map[string]string{
+		"Pecel Output":              "pecel-output",
+		"Table of Contents":         "table-of-contents",
+		"File 1: `main.go`":         "file-1-maingo",
+		"File 2: `cmd/my_tool.go`":  "file-2-cmdmy_toolgo",
+		"File 3: `a b-c.txt`":       "file-3-a-b-ctxt",
+		"File 4: `docs/Ünïcode.md`": "file-4-docsünïcodemd",
+		"  Spaced  ":                "--spaced--",
+	} {
+		if got := headingSlug(heading); got != want {
+			t.Errorf("headingSlug(%q) = %q, want %q", heading, got, want)
+		}
+	}
+}
+
+func TestSluggerDeduplicates(t *testing.T) {
+	for _, tc := range []struct {
+		headings []string
+		want     []string
+	}{
+		{[]string{"Content", "Content", "Content"}, []string{"content", "content-1", "content-2"}},
+		{[]string{"A", "B", "A"}, []string{"a", "b", "a-1"}},
+		// A literal "a-1" heading takes that anchor, so the repeat skips it
+		{[]string{"a", "a-1", "a"}, []string{"a", "a-1", "a-2"}},
+		{[]string{"a-1", "a", "a"}, []string{"a-1", "a", "a-2"}},
+		{[]string{"A!", "a"}, []string{"a", "a-1"}},
+	} {
+		slugs := slugger{}
+		var got []string
+		for _, heading := range tc.headings {
+			got = append(got, slugs.slug(heading))
+		}
+		if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+			t.Errorf("slugs of %q = %q, want %q", tc.headings, got, tc.want)
+		}
+	}
+}
+
+func TestMarkdownTOC(t *testing.T) {
+	config := defaultConfig()
+	config.CompactEmptySections = true
+	files := []FileInfo{
+		{RelativePath: "a.go", Content: "package a\n"},
+		{RelativePath: "empty.txt", Content: "\n"},
+		{RelativePath: "b.go", Content: "package b\n", ContentOmitted: true},
+	}
+	want := "## Table of Contents\n\n" +
+		"- [File 1: `a.go`](#file-1-ago)\n" +
+		"- File 2: `empty.txt` (empty)\n" +
+		"- [File 3: `b.go`](#file-3-bgo)\n\n"
+	if got := markdownTOC(files, config); got != want {
+		t.Errorf("markdownTOC =\n%s\nwant\n%s", got, want)
+	}
+}
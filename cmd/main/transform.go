@@ -0,0 +1,163 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// applyTransforms rewrites info.Content according to the content transforms
+// enabled in config. It runs after the file has been read and hashed, so
+// hashes always describe the file on disk.
+func applyTransforms(info *FileInfo, config Config) {
+	if config.GoAPIOnly && info.Encoding == "" && isGoSource(info.Path) {
+		if api, err := goAPISurface(info.Content); err == nil {
+			info.Content = api
+		} else if config.Verbose && !config.Quiet {
+			config.console.printf("%s Keeping all of %s: %v\n", yellow("⚠"), info.RelativePath, err)
+		}
+	}
+	if config.editorConfigs != nil && info.Path != stdinInput {
+		info.Content = applyEditorConfig(info.Content, config.editorConfigs.properties(info.Path))
+	}
+	original := info.Content
+	if config.PathPrefixLines {
+		info.Content = prefixLinesWithPath(info.Content, info.RelativePath)
+	}
+	// Blank runs are judged on the undecorated lines and dropped from both
+	// versions, so prefixed line numbers keep referring to the file
+	if config.MaxBlankLines > 0 {
+		keep := blankLineMask(original, config.MaxBlankLines)
+		original, info.Content = keepLines(original, keep), keepLines(info.Content, keep)
+	}
+	// Snippets are cut last so prefixed line numbers still refer to the file
+	if config.SnippetLines > 0 && config.contentRegex != nil {
+		info.Content = matchSnippets(original, info.Content, config.contentRegex, config.SnippetLines)
+	}
+}
+
+// blankLineMask marks which lines of content to keep so that no more than max
+// consecutive blank (empty or whitespace-only) lines remain.
+func blankLineMask(content string, max int) []bool {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	keep := make([]bool, len(lines))
+	run := 0
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			run++
+		} else {
+			run = 0
+		}
+		keep[i] = run <= max
+	}
+	return keep
+}
+
+// keepLines returns the lines of content that keep marks. content must have
+// one line per entry of keep; otherwise it is returned unchanged.
+func keepLines(content string, keep []bool) string {
+	if content == "" {
+		return content
+	}
+	trailingNewline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) != len(keep) {
+		return content
+	}
+	kept := lines[:0]
+	for i, line := range lines {
+		if keep[i] {
+			kept = append(kept, line)
+		}
+	}
+	result := strings.Join(kept, "\n")
+	if trailingNewline {
+		result += "\n"
+	}
+	return result
+}
+
+// snippetSeparator goes between non-adjacent snippets, as with grep -C.
+const snippetSeparator = "--"
+
+// matchSnippets returns the lines of rendered within context lines of a match
+// of re in original, merging overlapping snippets. rendered must have the same
+// lines as original; it may differ by per-line decoration such as path
+// prefixes. Matches spanning several lines keep all of them.
+func matchSnippets(original, rendered string, re *regexp.Regexp, context int) string {
+	matches := re.FindAllStringIndex(original, -1)
+	if len(matches) == 0 {
+		return rendered
+	}
+	lines := strings.Split(strings.TrimSuffix(rendered, "\n"), "\n")
+
+	// Line starts in original, to map match offsets to line numbers
+	starts := []int{0}
+	for i := 0; i < len(original); i++ {
+		if original[i] == '\n' && i+1 < len(original) {
+			starts = append(starts, i+1)
+		}
+	}
+	lineOf := func(offset int) int {
+		return sort.Search(len(starts), func(i int) bool { return starts[i] > offset }) - 1
+	}
+
+	var b strings.Builder
+	end := -1 // last line already written
+	for _, m := range matches {
+		last := m[1]
+		if last > m[0] {
+			last-- // the line holding the final matched byte
+		}
+		from, to := lineOf(m[0])-context, lineOf(last)+context
+		if from < 0 {
+			from = 0
+		}
+		if to >= len(lines) {
+			to = len(lines) - 1
+		}
+		if to <= end {
+			continue
+		}
+		if from <= end {
+			from = end + 1
+		} else if end >= 0 && from > end+1 {
+			b.WriteString(snippetSeparator + "\n")
+		}
+		for i := from; i <= to; i++ {
+			b.WriteString(lines[i])
+			b.WriteByte('\n')
+		}
+		end = to
+	}
+	return b.String()
+}
+
+// prefixLinesWithPath turns every line into "relpath:lineno:line", the format
+// grep -n and ripgrep --no-heading use, so matches in the bundle point straight
+// back to their source location.
+func prefixLinesWithPath(content, relPath string) string {
+	if content == "" {
+		return content
+	}
+	trailingNewline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+	var b strings.Builder
+	b.Grow(len(content) + len(lines)*(len(relPath)+8))
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(relPath)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(i + 1))
+		b.WriteByte(':')
+		b.WriteString(line)
+	}
+	if trailingNewline {
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// How files removed between the walk and the read are reported
+// (-vanished-files).
+const (
+	vanishedInfo   = "info"
+	vanishedIgnore = "ignore"
+	vanishedError  = "error"
+)
+
+// isVanished reports whether err means the file at path was removed after
+// the walk listed it, and that should not count as a failure. A dangling
+// symlink also fails with "not exist", but the link itself is still there.
+func isVanished(path string, err error, config Config) bool {
+	if config.VanishedFiles == vanishedError || !errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	_, err = lstatInput(path, config)
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// reportVanished notes a removed file as an informational event.
+func reportVanished(path string, config Config) {
+	if config.VanishedFiles == vanishedInfo && !config.Quiet {
+		config.console.printf("%s %s was removed after the scan; skipping\n", cyan("↳"), path)
+	}
+}
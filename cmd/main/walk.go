@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// parallelWalk is filepath.Walk with up to workers directories read at once.
+// fn is called for every entry exactly as filepath.Walk would call it, but
+// never concurrently, so it needs no locking of its own; the order of calls
+// across directories is not deterministic. Returning filepath.SkipDir for a
+// directory skips its contents; any other error stops the walk and is
+// returned. Directories are read from the input tree described by config.
+func parallelWalk(root string, workers int, config Config, fn filepath.WalkFunc) error {
+	var (
+		mu      sync.Mutex // serializes fn and guards walkErr
+		walkErr error
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, workers)
+	)
+
+	// visit calls fn and reports whether the walk should descend into path
+	visit := func(path string, info os.FileInfo, err error) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if walkErr != nil {
+			return false
+		}
+		if ferr := fn(path, info, err); ferr != nil {
+			if ferr != filepath.SkipDir {
+				walkErr = ferr
+			}
+			return false
+		}
+		return err == nil && info.IsDir()
+	}
+
+	var walkDir func(dir string, info os.FileInfo)
+	walkDir = func(dir string, info os.FileInfo) {
+		defer wg.Done()
+
+		sem <- struct{}{}
+		entries, err := readInputDir(dir, config)
+		infos := make([]os.FileInfo, len(entries))
+		infoErrs := make([]error, len(entries))
+		for i, entry := range entries {
+			infos[i], infoErrs[i] = entry.Info()
+		}
+		<-sem
+
+		if err != nil {
+			// Like filepath.Walk, report the unreadable directory a second time
+			visit(dir, info, err)
+			return
+		}
+		for i, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if infoErrs[i] != nil {
+				visit(path, nil, infoErrs[i])
+				continue
+			}
+			if visit(path, infos[i], nil) {
+				wg.Add(1)
+				go walkDir(path, infos[i])
+			}
+		}
+	}
+
+	info, err := lstatInput(root, config)
+	if visit(root, info, err) {
+		wg.Add(1)
+		walkDir(root, info)
+	}
+	wg.Wait()
+	return walkErr
+}
+
+// sortWalkOrder sorts entries into the order filepath.Walk visits them:
+// lexically by path component, so "a/b" comes before "a.txt".
+func sortWalkOrder(entries []walkEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return walkOrderLess(entries[i].Path, entries[j].Path)
+	})
+}
+
+// walkOrderLess compares two paths component by component.
+func walkOrderLess(a, b string) bool {
+	sep := string(filepath.Separator)
+	ac, bc := strings.Split(a, sep), strings.Split(b, sep)
+	for i := 0; i < len(ac) && i < len(bc); i++ {
+		if ac[i] != bc[i] {
+			return ac[i] < bc[i]
+		}
+	}
+	return len(ac) < len(bc)
+}
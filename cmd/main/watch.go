@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long watch mode waits for events to settle before
+// deciding whether to rebuild, so a burst of saves triggers one check.
+const watchDebounce = 300 * time.Millisecond
+
+// contentHashes maps each bundled file's path to a digest of its processed
+// content. Watch mode compares against it to ignore events that leave a
+// file's content as it was, such as an editor saving without changes.
+func contentHashes(fileInfos []FileInfo) map[string][sha256.Size]byte {
+	hashes := make(map[string][sha256.Size]byte, len(fileInfos))
+	for _, info := range fileInfos {
+		hashes[filepath.Clean(info.Path)] = sha256.Sum256([]byte(info.Content))
+	}
+	return hashes
+}
+
+// watch rebuilds the bundle whenever files under config.InputDir change,
+// until ctx is canceled. fileInfos is the result of the initial
+// build. Writes to bundled files only trigger a rebuild when their processed
+// content hashes differ from the last build; creates, removes and renames
+// always rebuild, since they can change which files are selected.
+func watch(ctx context.Context, config Config, excludeRegex, includeRegex *regexp.Regexp, previous, fileInfos []FileInfo) error {
+	if config.InputDir == stdinInput {
+		return fmt.Errorf("-watch cannot be used with stdin input")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watchTree(watcher, config.InputDir, config); err != nil {
+		return err
+	}
+
+	hashes := contentHashes(fileInfos)
+	changed := make(map[string]bool)
+	structural := false
+	var settle <-chan time.Time
+
+	if !config.Quiet {
+		fmt.Printf("\n%s Watching %s for changes (Ctrl+C to stop)\n", cyan("→"), config.InputDir)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if isOwnArtifact(event.Name, config) || event.Op == fsnotify.Chmod {
+				continue
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+				structural = true
+				if event.Op&fsnotify.Create != 0 {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						watchTree(watcher, event.Name, config)
+					}
+				}
+			} else {
+				changed[filepath.Clean(event.Name)] = true
+			}
+			settle = time.After(watchDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			if !config.Quiet {
+				fmt.Printf("%s Watch error: %v\n", yellow("⚠"), err)
+			}
+
+		case <-settle:
+			settle = nil
+			if !structural && !contentChanged(changed, hashes, config) {
+				if config.Verbose && !config.Quiet {
+					fmt.Printf("%s Change events without content changes; skipping rebuild\n", cyan("↳"))
+				}
+				changed = make(map[string]bool)
+				continue
+			}
+			changed = make(map[string]bool)
+			structural = false
+
+			if !config.Quiet {
+				fmt.Printf("\n%s Change detected, rebuilding\n", cyan("→"))
+			}
+			if config.RespectEditorConfig {
+				config.editorConfigs = newEditorConfigCache(inputOpener(config))
+			}
+			rebuilt, err := run(ctx, config, excludeRegex, includeRegex, previous, time.Now())
+			if ctx.Err() != nil {
+				return err
+			}
+			if err != nil {
+				fmt.Printf("%s Error %v\n", red("✗"), err)
+				continue
+			}
+			hashes = contentHashes(rebuilt)
+		}
+	}
+}
+
+// contentChanged reports whether any of the written paths is a bundled file
+// whose processed content no longer matches its recorded hash. Writes to files
+// outside the bundle are ignored.
+func contentChanged(paths map[string]bool, hashes map[string][sha256.Size]byte, config Config) bool {
+	for path := range paths {
+		old, bundled := hashes[path]
+		if !bundled {
+			continue
+		}
+		info, err := processSingleFile(path, config)
+		if err != nil || sha256.Sum256([]byte(info.Content)) != old {
+			return true
+		}
+	}
+	return false
+}
+
+// watchTree adds root and every directory below it to watcher, skipping the
+// directories the walk itself would prune.
+func watchTree(watcher *fsnotify.Watcher, root string, config Config) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if path != config.InputDir {
+			if (config.ExcludeHidden && isHidden(info.Name())) ||
+				(config.ExcludeVendored && vendoredDirs[info.Name()]) ||
+				isOwnArtifact(path, config) {
+				return filepath.SkipDir
+			}
+		}
+		return watcher.Add(path)
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeYAMLOutput writes -format yaml: the document writeJSONOutput produces,
+// with the same files and metadata keys, as YAML. Multi-line strings such as
+// file contents are written as literal block scalars so they stay readable.
+func writeYAMLOutput(fileInfos []FileInfo, writer io.Writer, config Config, stats Stats) (int64, error) {
+	generated := time.Now().Format(time.RFC3339)
+	tally := newStreamTally(stats, config)
+
+	files := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, info := range fileInfos {
+		tally.add(info)
+		node, err := yamlNode(info)
+		if err != nil {
+			return 0, err
+		}
+		files.Content = append(files.Content, node)
+	}
+	metadata, err := yamlNode(documentMetadata(tally.final(), stats, config, generated))
+	if err != nil {
+		return 0, err
+	}
+
+	doc := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "files"}, files,
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "metadata"}, metadata,
+	}}
+
+	counter := &countingWriter{w: writer}
+	encoder := yaml.NewEncoder(counter)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return counter.n, err
+	}
+	err = encoder.Close()
+	return counter.n, err
+}
+
+// yamlNode converts v to a YAML node through its JSON encoding, so the keys,
+// their order and omitempty match the JSON output.
+func yamlNode(v interface{}) (*yaml.Node, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	node := doc.Content[0]
+	blockStyle(node)
+	return node, nil
+}
+
+// blockStyle drops the flow and quoting styles a node parsed from JSON
+// carries, using literal block style for multi-line strings. The encoder
+// still quotes strings that would otherwise read as another type.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && strings.Contains(node.Value, "\n") {
+		node.Style = yaml.LiteralStyle
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
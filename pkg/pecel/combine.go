@@ -0,0 +1,134 @@
+package pecel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options selects the files Combine bundles and the format it writes.
+type Options struct {
+	// InputDir is the directory walked; "" means the current directory.
+	InputDir string
+	// Format names a format added with RegisterFormat.
+	Format string
+	// Extensions limits the bundle to files with these extensions, e.g.
+	// ".go"; empty includes every extension.
+	Extensions []string
+	// IncludeHidden keeps files and directories whose names start with a
+	// dot, which are left out by default as in the pecel command.
+	IncludeHidden bool
+	// MaxFileSize leaves out files larger than this many bytes; 0 means no
+	// limit.
+	MaxFileSize int64
+}
+
+// binarySniffSize is how much of a file is checked for NUL bytes before it
+// is taken for text.
+const binarySniffSize = 8000
+
+// Combine walks opts.InputDir and writes its text files to w in the format
+// registered under opts.Format. Binary files, which contain a NUL byte in
+// their first 8000 bytes, are left out, and files that cannot be read are
+// counted in Stats.Errors. The built-in formats of the pecel command are
+// rendered by the command itself, so Combine only knows registered ones.
+func Combine(opts Options, w io.Writer) (Stats, error) {
+	var stats Stats
+	writer, ok := LookupFormat(opts.Format)
+	if !ok {
+		return stats, fmt.Errorf("pecel: format %q is not registered", opts.Format)
+	}
+	root := opts.InputDir
+	if root == "" {
+		root = "."
+	}
+	extensions := make(map[string]bool, len(opts.Extensions))
+	for _, ext := range opts.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions[strings.ToLower(ext)] = true
+	}
+
+	start := time.Now()
+	var files []FileInfo
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		hidden := path != root && strings.HasPrefix(d.Name(), ".")
+		if d.IsDir() {
+			if hidden && !opts.IncludeHidden {
+				return filepath.SkipDir
+			}
+			stats.Directories++
+			return nil
+		}
+		if !d.Type().IsRegular() || hidden && !opts.IncludeHidden {
+			return nil
+		}
+		if len(extensions) > 0 && !extensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			stats.Errors++
+			return nil
+		}
+		if opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			stats.Errors++
+			return nil
+		}
+		sniff := content
+		if len(sniff) > binarySniffSize {
+			sniff = sniff[:binarySniffSize]
+		}
+		if bytes.IndexByte(sniff, 0) >= 0 {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		file := FileInfo{
+			Path:         path,
+			Size:         info.Size(),
+			Modified:     info.ModTime().Format("2006-01-02 15:04:05"),
+			Content:      string(content),
+			RelativePath: filepath.ToSlash(rel),
+			LineCount:    countLines(content),
+		}
+		files = append(files, file)
+		stats.FilesProcessed++
+		stats.TotalBytes += file.Size
+		stats.TotalLines += file.LineCount
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	stats.Duration = time.Since(start).Seconds()
+
+	_, err = writer.Write(files, w, stats)
+	return stats, err
+}
+
+// countLines counts the lines in content, including a last line without a
+// trailing newline.
+func countLines(content []byte) int {
+	lines := bytes.Count(content, []byte{'\n'})
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
@@ -0,0 +1,45 @@
+// Package pecel is the library side of the pecel command. Programs can
+// register their own output formats with RegisterFormat and bundle a
+// directory in one of them with Combine. The command consults the same
+// registry after its built-in formats, so a build of it that imports a
+// package registering a format offers that format as -format.
+package pecel
+
+// FileInfo is one file of a bundle as a FormatWriter receives it.
+type FileInfo struct {
+	Path         string `json:"path" xml:"path"`
+	Size         int64  `json:"size" xml:"size"`
+	Modified     string `json:"modified" xml:"modified"`
+	Content      string `json:"content,omitempty" xml:"content,omitempty"`
+	RelativePath string `json:"relative_path" xml:"relative_path"`
+	Hash         string `json:"hash,omitempty" xml:"hash,omitempty"`
+	DiffStatus   string `json:"diff_status,omitempty" xml:"diff_status,omitempty"`
+	ContentType  string `json:"content_type,omitempty" xml:"content_type,omitempty"`
+	LinkTarget   string `json:"link_target,omitempty" xml:"link_target,omitempty"`
+	// ContentOmitted marks metadata-only entries (-content-max-depth).
+	ContentOmitted bool `json:"content_omitted,omitempty" xml:"content_omitted,omitempty"`
+	// ContentRef points to the blob holding the content when it is not
+	// inlined (-externalize-content).
+	ContentRef string `json:"content_ref,omitempty" xml:"content_ref,omitempty"`
+	// LineCount is the number of lines in the file.
+	LineCount int `json:"line_count,omitempty" xml:"line_count,omitempty"`
+	// Index is the file's 1-based position in the bundle (-number-files).
+	Index int `json:"index,omitempty" xml:"index,omitempty"`
+	// Encoding is "hexdump" or "base64" when Content encodes a binary file.
+	Encoding string `json:"encoding,omitempty" xml:"encoding,omitempty"`
+	// LastAuthor and LastCommitDate describe the last commit to touch the
+	// file (-git-author).
+	LastAuthor     string `json:"last_author,omitempty" xml:"last_author,omitempty"`
+	LastCommitDate string `json:"last_commit_date,omitempty" xml:"last_commit_date,omitempty"`
+}
+
+// Stats summarizes the run that produced a bundle.
+type Stats struct {
+	FilesProcessed int     `json:"files_processed"`
+	Directories    int     `json:"directories"`
+	TotalBytes     int64   `json:"total_bytes"`
+	TotalLines     int     `json:"total_lines,omitempty"`
+	Duration       float64 `json:"duration_seconds"`
+	// Errors counts files that could not be read.
+	Errors int `json:"errors"`
+}
@@ -0,0 +1,73 @@
+package pecel
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FormatWriter renders a bundle in a format added with RegisterFormat. It
+// returns the number of bytes written to w.
+type FormatWriter interface {
+	Write(files []FileInfo, w io.Writer, stats Stats) (int64, error)
+}
+
+var (
+	formatsMu sync.RWMutex
+	// formats holds the writers added with RegisterFormat, by lower-cased
+	// name.
+	formats = map[string]FormatWriter{}
+)
+
+// builtinFormats are the names the pecel command renders itself, including
+// their aliases; they cannot be registered.
+var builtinFormats = map[string]bool{
+	"text": true, "json": true, "jsonl": true, "xml": true, "markdown": true, "md": true,
+	"html-app": true, "html": true, "csv": true, "yaml": true, "yml": true, "sqlite": true,
+}
+
+// RegisterFormat makes writer available under name, which is matched
+// case-insensitively. It fails if writer is nil, the name is empty, or the
+// name is taken by a built-in format or an earlier registration.
+func RegisterFormat(name string, writer FormatWriter) error {
+	if writer == nil {
+		return fmt.Errorf("pecel: format %q has a nil writer", name)
+	}
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		return fmt.Errorf("pecel: format name is empty")
+	}
+	if builtinFormats[key] {
+		return fmt.Errorf("pecel: format %q is built in", name)
+	}
+
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if _, dup := formats[key]; dup {
+		return fmt.Errorf("pecel: format %q is already registered", name)
+	}
+	formats[key] = writer
+	return nil
+}
+
+// LookupFormat returns the writer registered under name.
+func LookupFormat(name string) (FormatWriter, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	writer, ok := formats[strings.ToLower(name)]
+	return writer, ok
+}
+
+// Formats returns the registered format names, sorted.
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
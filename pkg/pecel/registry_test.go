@@ -0,0 +1,99 @@
+package pecel_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bhangun/pecel/pkg/pecel"
+)
+
+// listWriter writes one "relative-path lines" line per file and a total.
+type listWriter struct{}
+
+func (listWriter) Write(files []pecel.FileInfo, w io.Writer, stats pecel.Stats) (int64, error) {
+	var b strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&b, "%s %d\n", f.RelativePath, f.LineCount)
+	}
+	fmt.Fprintf(&b, "total %d files, %d bytes\n", stats.FilesProcessed, stats.TotalBytes)
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func TestRegisterFormat(t *testing.T) {
+	if err := pecel.RegisterFormat("List", listWriter{}); err != nil {
+		t.Fatalf("RegisterFormat: %v", err)
+	}
+	if _, ok := pecel.LookupFormat("list"); !ok {
+		t.Fatal("LookupFormat does not find the registered format")
+	}
+	found := false
+	for _, name := range pecel.Formats() {
+		found = found || name == "list"
+	}
+	if !found {
+		t.Errorf("Formats() = %v, want it to include list", pecel.Formats())
+	}
+
+	for _, tc := range []struct {
+		name   string
+		writer pecel.FormatWriter
+	}{
+		{"list", listWriter{}},
+		{"LIST", listWriter{}},
+		{"json", listWriter{}},
+		{"", listWriter{}},
+		{"other", nil},
+	} {
+		if err := pecel.RegisterFormat(tc.name, tc.writer); err == nil {
+			t.Errorf("RegisterFormat(%q, %v) succeeded, want an error", tc.name, tc.writer)
+		}
+	}
+}
+
+func TestCombine(t *testing.T) {
+	if err := pecel.RegisterFormat("combine-list", listWriter{}); err != nil {
+		t.Fatalf("RegisterFormat: %v", err)
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.go":            "package a\n\nfunc A() {}\n",
+		"sub/b.go":        "package b",
+		"sub/notes.txt":   "skipped by extension\n",
+		".hidden/c.go":    "package c\n",
+		"bin.go":          "package\x00bin\n",
+		"sub/.secret.go":  "package secret\n",
+		"sub/deep/d.go":   "package d\n",
+		"sub/deep/e.json": "{}\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	stats, err := pecel.Combine(pecel.Options{InputDir: dir, Format: "combine-list", Extensions: []string{"go"}}, &out)
+	if err != nil {
+		t.Fatalf("Combine: %v", err)
+	}
+	want := "a.go 3\nsub/b.go 1\nsub/deep/d.go 1\ntotal 3 files, 42 bytes\n"
+	if out.String() != want {
+		t.Errorf("Combine wrote\n%s\nwant\n%s", out.String(), want)
+	}
+	if stats.FilesProcessed != 3 || stats.TotalLines != 5 {
+		t.Errorf("stats = %+v, want 3 files and 5 lines", stats)
+	}
+
+	if _, err := pecel.Combine(pecel.Options{InputDir: dir, Format: "no-such-format"}, io.Discard); err == nil {
+		t.Error("Combine with an unregistered format succeeded, want an error")
+	}
+}